@@ -12,6 +12,8 @@ import (
 	"github.com/douyu/juno/internal/pkg/service/agent"
 	"github.com/douyu/juno/internal/pkg/service/appevent"
 	"github.com/douyu/juno/internal/pkg/service/clientproxy"
+	"github.com/douyu/juno/internal/pkg/service/confgov2/rollout"
+	"github.com/douyu/juno/internal/pkg/service/confgov2/validate"
 	"github.com/douyu/juno/internal/pkg/service/configresource"
 	"github.com/douyu/juno/internal/pkg/service/openauth"
 	"github.com/douyu/juno/internal/pkg/service/resource"
@@ -35,21 +37,30 @@ const (
 	queryAgentUsedStatus = "/api/v1/conf/command_line/status"
 )
 
-func List(param view.ReqListConfig) (resp view.RespListConfig, err error) {
+func List(c echo.Context, param view.ReqListConfig) (resp view.RespListConfig, err error) {
 	var app db.AppInfo
 
 	resp = make(view.RespListConfig, 0)
 	list := make([]db.Configuration, 0)
 
+	if _, err = requireAction(c, ActionRead, param.AppName, param.Env, ""); err != nil {
+		return resp, err
+	}
+
 	err = mysql.Where("app_name = ?", param.AppName).First(&app).Error
 	if err != nil {
 		return resp, err
 	}
 
-	err = mysql.Select("id, aid, name, format, env, zone, created_at, updated_at, published_at").
+	query := mysql.Select("id, aid, name, format, env, zone, created_at, updated_at, published_at, deleted_at").
 		Where("aid = ?", app.Aid).
-		Where("env = ?", param.Env).
-		Find(&list).Error
+		Where("env = ?", param.Env)
+	if !param.Deleted {
+		query = query.Where("deleted_at is null")
+	} else {
+		query = query.Where("deleted_at is not null")
+	}
+	err = query.Find(&list).Error
 
 	for _, item := range list {
 		resp = append(resp, view.RespListConfigItem{
@@ -69,12 +80,21 @@ func List(param view.ReqListConfig) (resp view.RespListConfig, err error) {
 	return
 }
 
-func Detail(param view.ReqDetailConfig) (resp view.RespDetailConfig, err error) {
+func Detail(c echo.Context, param view.ReqDetailConfig) (resp view.RespDetailConfig, err error) {
 	configuration := db.Configuration{}
 	err = mysql.Where("id = ?", param.ID).First(&configuration).Error
 	if err != nil {
 		return
 	}
+
+	appInfo, err := resource.Resource.GetApp(int(configuration.AID))
+	if err != nil {
+		return
+	}
+	if _, err = requireAction(c, ActionRead, appInfo.AppName, configuration.Env, configuration.Zone); err != nil {
+		return
+	}
+
 	resp = view.RespDetailConfig{
 		ID:          configuration.ID,
 		AID:         configuration.AID,
@@ -91,10 +111,14 @@ func Detail(param view.ReqDetailConfig) (resp view.RespDetailConfig, err error)
 }
 
 // Create ..
-func Create(param view.ReqCreateConfig) (resp view.RespDetailConfig, err error) {
+func Create(c echo.Context, param view.ReqCreateConfig) (resp view.RespDetailConfig, err error) {
 	var app db.AppInfo
 	var appNode db.AppNode
 
+	if _, err = requireAction(c, ActionEditDraft, param.AppName, param.Env, param.Zone); err != nil {
+		return
+	}
+
 	// 验证应用是否存在
 	err = mysql.Where("app_name = ?", param.AppName).First(&app).Error
 	if err != nil {
@@ -177,6 +201,15 @@ func Update(c echo.Context, param view.ReqUpdateConfig) (err error) {
 		return err
 	}
 
+	appInfo, err := resource.Resource.GetApp(int(configuration.AID))
+	if err != nil {
+		return err
+	}
+	operator, err := requireAction(c, ActionEditDraft, appInfo.AppName, configuration.Env, configuration.Zone)
+	if err != nil {
+		return err
+	}
+
 	newContent := configresource.FillConfigResource(param.Content)
 	oldContent := configresource.FillConfigResource(configuration.Content)
 
@@ -186,6 +219,25 @@ func Update(c echo.Context, param view.ReqUpdateConfig) (err error) {
 		return fmt.Errorf("保存失败，本次无更新")
 	}
 
+	report := buildValidatorChain(configuration, nil).Run(context.Background(), validate.Input{
+		AID:     configuration.AID,
+		Name:    configuration.Name,
+		Format:  configuration.Format,
+		Content: param.Content,
+	})
+	if err = saveValidationResult(configuration, report); err != nil {
+		return err
+	}
+	if report.HasErrors() {
+		perms, permErr := effectivePermissions(operator, appInfo.AppName, configuration.Env, configuration.Zone)
+		if permErr != nil {
+			return permErr
+		}
+		if !perms[ActionOverrideValidation] {
+			return fmt.Errorf("配置校验未通过: %+v", report.Errors)
+		}
+	}
+
 	history := db.ConfigurationHistory{
 		ConfigurationID: configuration.ID,
 		ChangeLog:       param.Message,
@@ -383,9 +435,7 @@ func assemblyJunoAgent(nodes []db.AppNode) []view.JunoAgent {
 }
 
 // Publish ..
-func Publish(param view.ReqPublishConfig, user *db.User) (err error) {
-	// Complete configuration release logic
-
+func Publish(c echo.Context, param view.ReqPublishConfig, user *db.User) (err error) {
 	// Get configuration
 	var configuration db.Configuration
 	query := mysql.Where("id=?", param.ID).Find(&configuration)
@@ -393,6 +443,34 @@ func Publish(param view.ReqPublishConfig, user *db.User) (err error) {
 		return query.Error
 	}
 
+	appInfo, err := resource.Resource.GetApp(int(configuration.AID))
+	if err != nil {
+		return
+	}
+
+	publishAction := ActionPublishNonProd
+	if isProdZone(configuration.Env) {
+		publishAction = ActionPublishProd
+	}
+
+	operator, err := requireAction(c, publishAction, appInfo.AppName, configuration.Env, configuration.Zone)
+	if err != nil {
+		return
+	}
+
+	if isProdZone(configuration.Env) {
+		if err = requireApprover(param.ApproverUID, operator, appInfo.AppName, configuration.Env, configuration.Zone); err != nil {
+			return
+		}
+	}
+
+	return doPublish(operator, configuration, appInfo, param, user)
+}
+
+// doPublish 是 Publish 真正的发布逻辑，调用方（在线请求的 Publish，或定时发布的调度器）需要先自行完成鉴权，
+// 这里负责发布前校验（校验未通过时检查 override_validation 权限）、写 etcd 和落库
+func doPublish(operator caller, configuration db.Configuration, appInfo db.AppInfo, param view.ReqPublishConfig, user *db.User) (err error) {
+	// Complete configuration release logic
 	aid := int(configuration.AID)
 	env := configuration.Env
 	zoneCode := configuration.Zone
@@ -400,7 +478,7 @@ func Publish(param view.ReqPublishConfig, user *db.User) (err error) {
 
 	// Get publish version
 	var confHistory db.ConfigurationHistory
-	query = mysql.Where("configuration_id=? and version =?", param.ID, param.Version).Find(&confHistory)
+	query := mysql.Where("configuration_id=? and version =?", param.ID, param.Version).Find(&confHistory)
 	if query.Error != nil {
 		return query.Error
 	}
@@ -408,21 +486,46 @@ func Publish(param view.ReqPublishConfig, user *db.User) (err error) {
 	content := confHistory.Content
 	version := confHistory.Version
 
-	// resource filter
-	content = configresource.FillConfigResource(content)
 	// Get nodes data
 	var instanceList []string
 	if instanceList, err = getPublishInstance(aid, env, zoneCode); err != nil {
 		return
 	}
 
-	// Obtain application management port
-	appInfo, err := resource.Resource.GetApp(aid)
-	if err != nil {
+	// 校验必须跑在资源占位符替换之前，否则 ResourceValidator 永远看不到 ${resource.xxx} 占位符，
+	// 引用不存在/被删除的资源这类问题就会被放过，和 Update 阶段的校验口径保持一致
+	report := buildValidatorChain(configuration, newAgentConfigCheck(appInfo.AppName, instanceList)).
+		Run(context.Background(), validate.Input{
+			AID:     configuration.AID,
+			Name:    configuration.Name,
+			Format:  configuration.Format,
+			Content: content,
+		})
+	if err = saveValidationResult(configuration, report); err != nil {
 		return
 	}
+	if report.HasErrors() {
+		perms, permErr := effectivePermissions(operator, appInfo.AppName, env, zoneCode)
+		if permErr != nil {
+			err = permErr
+			return
+		}
+		if !perms[ActionOverrideValidation] {
+			err = fmt.Errorf("配置校验未通过，禁止发布: %+v", report.Errors)
+			return
+		}
+	}
+
+	// resource filter
+	content = configresource.FillConfigResource(content)
+
+	// Save the configuration in etcd, either all at once or through a staged rollout. 灰度发布的
+	// ConfigurationPublish/ConfigurationStatus 由 rollout worker 在每批写入 etcd 时按该批 hosts 落库，
+	// 发布事件在该批被判定推进后才广播（见 startRollout 里注入的 publish/record），这里不按全量 instanceList 落库
+	if param.Strategy != nil && len(param.Strategy.Waves) > 0 {
+		return startRollout(configuration, appInfo, confHistory, content, version, instanceList, param.Strategy, param.ApproverUID, user)
+	}
 
-	// Save the configuration in etcd
 	if err = publishETCD(view.ReqConfigPublish{
 		AppName:      appInfo.AppName,
 		ZoneCode:     zoneCode,
@@ -447,6 +550,7 @@ func Publish(param view.ReqPublishConfig, user *db.User) (err error) {
 		cp.ConfigurationID = configuration.ID
 		cp.ConfigurationHistoryID = confHistory.ID
 		cp.UID = uint(user.Uid)
+		cp.ApproverUID = param.ApproverUID
 		_, cp.FilePath = genConfigurePath(appInfo.AppName, configuration.FileName())
 		if err = tx.Save(&cp).Error; err != nil {
 			tx.Rollback()
@@ -558,6 +662,242 @@ func publishETCD(req view.ReqConfigPublish) (err error) {
 	return nil
 }
 
+// publishETCDWave 和 publishETCD 写入相同的 etcd 结构，区别在于只覆盖 hosts 这一批实例，
+// 且各实例并发写入，供灰度发布的 rollout worker 按批调用
+func publishETCDWave(req view.ReqConfigPublish) (err error) {
+	content := configurationHeader(req.Content, req.Format, req.Version)
+	paths, _ := genConfigurePath(req.AppName, req.FileName)
+	data := view.ConfigurationPublishData{
+		Content: content,
+		Metadata: view.Metadata{
+			Timestamp: time.Now().Unix(),
+			Format:    req.Format,
+			Version:   req.Version,
+			Paths:     paths,
+		},
+	}
+
+	buf, err := json.Marshal(data)
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second*3)
+	defer cancel()
+
+	var eg errgroup.Group
+	for _, hostName := range req.InstanceList {
+		hostName := hostName
+		eg.Go(func() error {
+			for _, prefix := range cfg.Cfg.Configure.Prefixes {
+				key := fmt.Sprintf("/%s/%s/%s/%s/static/%s/%s", prefix, hostName, req.AppName, req.Env, req.FileName, req.Port)
+				if _, err := clientproxy.ClientProxy.EtcdPut(view.UniqZone{Env: req.Env, Zone: req.ZoneCode}, ctx, key, string(buf)); err != nil {
+					return err
+				}
+
+				clusterKey := fmt.Sprintf("/%s/cluster/%s/%s/static/%s", prefix, req.AppName, req.Env, req.FileName)
+				if _, err := clientproxy.ClientProxy.EtcdPut(view.UniqZone{Env: req.Env, Zone: req.ZoneCode}, ctx, clusterKey, string(buf)); err != nil {
+					return err
+				}
+			}
+			return nil
+		})
+	}
+
+	return eg.Wait()
+}
+
+// startRollout 组装灰度发布所需的写入/状态轮询/回滚回调，落地 ConfigurationRollout 记录并启动后台 worker
+func startRollout(configuration db.Configuration, appInfo db.AppInfo, confHistory db.ConfigurationHistory, content, version string, instanceList []string, strategy *view.RolloutStrategy, approverUID uint, user *db.User) error {
+	waves := make([]rollout.Wave, 0, len(strategy.Waves))
+	for _, w := range strategy.Waves {
+		waves = append(waves, rollout.Wave{
+			Percent: w.Percent,
+			Hosts:   w.Hosts,
+			MinBake: w.MinBake,
+		})
+	}
+
+	// 记下发布前的版本内容，发布异常时用于回滚
+	var previousContent, previousVersion string
+	var previousPublish db.ConfigurationPublish
+	if mysql.Where("configuration_id = ?", configuration.ID).Order("id desc").First(&previousPublish).Error == nil {
+		var previousHistory db.ConfigurationHistory
+		if mysql.Where("id = ?", previousPublish.ConfigurationHistoryID).First(&previousHistory).Error == nil {
+			previousContent = configresource.FillConfigResource(previousHistory.Content)
+			previousVersion = previousHistory.Version
+		}
+	}
+
+	cr := db.ConfigurationRollout{
+		ConfigurationID: configuration.ID,
+		State:           string(rollout.StateRunning),
+		WaveCount:       len(waves),
+	}
+	if err := mysql.Save(&cr).Error; err != nil {
+		return err
+	}
+
+	// ConfigurationPublish 只建一次（首批写入时），之后每批在写 etcd 成功后立刻为该批 hosts 建
+	// ConfigurationStatus（TakeEffect=0）占位，agent 上报和 status() 轮询读的就是这些行——
+	// 必须在 waitHealthy 开始轮询之前就把行建出来，否则轮询永远查不到任何记录，灰度发布会卡在第一批
+	var cp db.ConfigurationPublish
+	publish := func(hosts []string) error {
+		if err := publishETCDWave(view.ReqConfigPublish{
+			AppName:      appInfo.AppName,
+			ZoneCode:     configuration.Zone,
+			Port:         appInfo.GovernPort,
+			FileName:     configuration.FileName(),
+			Format:       configuration.Format,
+			Content:      content,
+			InstanceList: hosts,
+			Env:          configuration.Env,
+			Version:      version,
+		}); err != nil {
+			return err
+		}
+
+		if cp.ID == 0 {
+			instanceListJSON, _ := json.Marshal(instanceList)
+			cp.ApplyInstance = string(instanceListJSON)
+			cp.ConfigurationID = configuration.ID
+			cp.ConfigurationHistoryID = confHistory.ID
+			cp.UID = uint(user.Uid)
+			cp.ApproverUID = approverUID
+			_, cp.FilePath = genConfigurePath(appInfo.AppName, configuration.FileName())
+			if err := mysql.Save(&cp).Error; err != nil {
+				return err
+			}
+		}
+
+		for _, instance := range hosts {
+			var cs db.ConfigurationStatus
+			cs.ConfigurationID = configuration.ID
+			cs.ConfigurationPublishID = cp.ID
+			cs.HostName = instance
+			cs.Used = 0
+			cs.Synced = 0
+			cs.TakeEffect = 0
+			cs.CreatedAt = time.Now()
+			cs.UpdateAt = time.Now()
+			if err := mysql.Save(&cs).Error; err != nil {
+				return err
+			}
+		}
+
+		return nil
+	}
+
+	status := func(hosts []string) (float64, error) {
+		if len(hosts) == 0 {
+			return 1, nil
+		}
+		succeeded := 0
+		for _, host := range hosts {
+			s, err := getConfigurationStatus(configuration.ID, host)
+			if err != nil {
+				continue
+			}
+			if s.TakeEffect == 1 {
+				succeeded++
+			}
+		}
+		return float64(succeeded) / float64(len(hosts)), nil
+	}
+
+	rollback := func() error {
+		if previousContent == "" {
+			return nil
+		}
+		return publishETCDWave(view.ReqConfigPublish{
+			AppName:      appInfo.AppName,
+			ZoneCode:     configuration.Zone,
+			Port:         appInfo.GovernPort,
+			FileName:     configuration.FileName(),
+			Format:       configuration.Format,
+			Content:      previousContent,
+			InstanceList: instanceList,
+			Env:          configuration.Env,
+			Version:      previousVersion,
+		})
+	}
+
+	onTransition := func(waveIndex int, state rollout.State, message string) {
+		mysql.Model(&db.ConfigurationRollout{}).Where("id = ?", cr.ID).
+			Updates(map[string]interface{}{"wave_index": waveIndex, "state": string(state), "message": message})
+	}
+
+	// record 在某一批次 waitHealthy 通过、被判定为"已推进"之后调用：这一批的 ConfigurationStatus 行
+	// 在 publish() 里已经建好了，这里只需要对外广播这一批的发布事件
+	record := func(hosts []string) error {
+		meta, _ := json.Marshal(cp)
+		appevent.AppEvent.ConfgoFilePublishEvent(appInfo.Aid, appInfo.AppName, configuration.Env, configuration.Zone, string(meta), user)
+		return nil
+	}
+
+	return rollout.Start(context.Background(), rollout.Request{
+		ConfigurationID: configuration.ID,
+		AllHosts:        instanceList,
+		Waves:           waves,
+		MinSuccessRatio: strategy.MinSuccessRatio,
+		PollInterval:    strategy.PollInterval,
+		Record:          record,
+		Publish:         publish,
+		Status:          status,
+		Rollback:        rollback,
+		OnTransition:    onTransition,
+	})
+}
+
+// PauseRollout 暂停一次正在进行中的灰度发布
+func PauseRollout(c echo.Context, configID uint) error {
+	if err := requireRolloutAction(c, ActionPublishProd, configID); err != nil {
+		return err
+	}
+	return rollout.PauseRollout(configID)
+}
+
+// ResumeRollout 恢复一次已暂停的灰度发布
+func ResumeRollout(c echo.Context, configID uint) error {
+	if err := requireRolloutAction(c, ActionPublishProd, configID); err != nil {
+		return err
+	}
+	return rollout.ResumeRollout(configID)
+}
+
+// AbortRollout 中止一次灰度发布，已经写入的实例会被改写回上一次发布的版本；该配置此前没有发布记录时
+// 不做任何回退，已写入的 etcd key 保持新版本内容，不会被删除
+func AbortRollout(c echo.Context, configID uint) error {
+	if err := requireRolloutAction(c, ActionRollback, configID); err != nil {
+		return err
+	}
+	return rollout.AbortRollout(configID)
+}
+
+// GetRolloutStatus 查询灰度发布的当前状态
+func GetRolloutStatus(c echo.Context, configID uint) (rollout.Status, error) {
+	if err := requireRolloutAction(c, ActionRead, configID); err != nil {
+		return rollout.Status{}, err
+	}
+	return rollout.GetRolloutStatus(configID)
+}
+
+// requireRolloutAction 根据 configID 反查所属 app/env/zone 后做权限校验，供灰度发布的控制类 API 复用
+func requireRolloutAction(c echo.Context, action Action, configID uint) error {
+	var configuration db.Configuration
+	if err := mysql.Where("id = ?", configID).First(&configuration).Error; err != nil {
+		return err
+	}
+
+	appInfo, err := resource.Resource.GetApp(int(configuration.AID))
+	if err != nil {
+		return err
+	}
+
+	_, err = requireAction(c, action, appInfo.AppName, configuration.Env, configuration.Zone)
+	return err
+}
+
 // History 发布历史分页列表，Page从0开始
 func History(param view.ReqHistoryConfig, uid int) (resp view.RespHistoryConfig, err error) {
 	list := make([]db.ConfigurationHistory, 0)
@@ -690,12 +1030,45 @@ func Diff(configID, historyID uint) (resp view.RespDiffConfig, err error) {
 }
 
 // Delete ..
-func Delete(id uint) (err error) {
-	err = mysql.Delete(&db.Configuration{}, "id = ?", id).Error
+func Delete(c echo.Context, id uint) (err error) {
+	var configuration db.Configuration
+	if err = mysql.Where("id = ?", id).First(&configuration).Error; err != nil {
+		return
+	}
+
+	appInfo, err := resource.Resource.GetApp(int(configuration.AID))
+	if err != nil {
+		return
+	}
+	if _, err = requireAction(c, ActionDelete, appInfo.AppName, configuration.Env, configuration.Zone); err != nil {
+		return
+	}
+
+	// 软删除：保留内容与历史，方便 RestoreDeleted 撤销
+	err = mysql.Model(&db.Configuration{}).Where("id = ?", id).Update("deleted_at", time.Now()).Error
+	return
+}
+
+// RestoreDeleted 撤销一次软删除，要求调用者具备 delete 权限（和 Delete 同一套权限控制）
+func RestoreDeleted(c echo.Context, id uint) (err error) {
+	var configuration db.Configuration
+	if err = mysql.Unscoped().Where("id = ?", id).First(&configuration).Error; err != nil {
+		return
+	}
+
+	appInfo, err := resource.Resource.GetApp(int(configuration.AID))
+	if err != nil {
+		return
+	}
+	if _, err = requireAction(c, ActionDelete, appInfo.AppName, configuration.Env, configuration.Zone); err != nil {
+		return
+	}
+
+	err = mysql.Unscoped().Model(&db.Configuration{}).Where("id = ?", id).Update("deleted_at", nil).Error
 	return
 }
 
-func ReadInstanceConfig(param view.ReqReadInstanceConfig) (configContentList []view.RespReadInstanceConfigItem, err error) {
+func ReadInstanceConfig(c echo.Context, param view.ReqReadInstanceConfig) (configContentList []view.RespReadInstanceConfigItem, err error) {
 	var config db.Configuration
 	var app db.AppInfo
 	var node db.AppNode
@@ -710,6 +1083,10 @@ func ReadInstanceConfig(param view.ReqReadInstanceConfig) (configContentList []v
 		return
 	}
 
+	if _, err = requireAction(c, ActionRead, app.AppName, config.Env, config.Zone); err != nil {
+		return
+	}
+
 	err = mysql.Where("app_name = ?", app.AppName).Where("host_name = ?", param.HostName).First(&node).Error
 	if err != nil {
 		return
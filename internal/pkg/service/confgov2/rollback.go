@@ -0,0 +1,119 @@
+package confgov2
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/douyu/juno/internal/pkg/service/appevent"
+	"github.com/douyu/juno/internal/pkg/service/configresource"
+	"github.com/douyu/juno/internal/pkg/service/resource"
+	"github.com/douyu/juno/pkg/model/db"
+	"github.com/douyu/juno/pkg/model/view"
+	"github.com/douyu/juno/pkg/util"
+	"github.com/labstack/echo/v4"
+)
+
+// Rollback 把 configID 回滚到 targetHistoryID 这条历史快照：先生成一条新的历史记录（而不是就地
+// 篡改旧记录），再走一次标准 Publish 流程，让 etcd/ConfigurationPublish/ConfigurationStatus
+// 都按发布规则重新落地
+func Rollback(c echo.Context, configID, targetHistoryID uint, approverUID uint, user *db.User) (err error) {
+	var configuration db.Configuration
+	if err = mysql.Where("id = ?", configID).First(&configuration).Error; err != nil {
+		return
+	}
+
+	var target db.ConfigurationHistory
+	if err = mysql.Where("id = ? and configuration_id = ?", targetHistoryID, configID).First(&target).Error; err != nil {
+		return
+	}
+
+	appInfo, err := resource.Resource.GetApp(int(configuration.AID))
+	if err != nil {
+		return
+	}
+
+	if _, err = requireAction(c, ActionRollback, appInfo.AppName, configuration.Env, configuration.Zone); err != nil {
+		return
+	}
+
+	newContent := configresource.FillConfigResource(target.Content)
+	version := util.Md5Str(newContent)
+	if version == configuration.Version {
+		return fmt.Errorf("回滚失败：目标版本与当前内容一致")
+	}
+
+	history := db.ConfigurationHistory{
+		ConfigurationID: configuration.ID,
+		ChangeLog:       fmt.Sprintf("回滚至历史版本 #%d", targetHistoryID),
+		Content:         target.Content,
+		Version:         version,
+		UID:             uint(user.Uid),
+	}
+	if err = mysql.Save(&history).Error; err != nil {
+		return
+	}
+
+	// 先走标准发布流程，发布成功后再落地 configuration.Content/Version：如果先改 configuration 再发布，
+	// Publish 因鉴权/审批/校验失败时数据库会显示“已回滚”，但 etcd 和发布记录其实都没变，是半成品状态
+	if err = Publish(c, view.ReqPublishConfig{
+		ID:          configID,
+		Version:     version,
+		ApproverUID: approverUID,
+	}, user); err != nil {
+		return
+	}
+
+	if err = mysql.Model(&db.Configuration{}).Where("id = ?", configID).
+		Updates(map[string]interface{}{"content": target.Content, "version": version}).Error; err != nil {
+		return
+	}
+
+	meta, _ := json.Marshal(history)
+	appevent.AppEvent.ConfgoFileRollbackEvent(appInfo.Aid, appInfo.AppName, configuration.Env, configuration.Zone, string(meta), user)
+
+	return nil
+}
+
+// PreviewRollback 返回当前配置内容与目标历史版本之间的 diff，结构与 Diff 保持一致，方便前端复用同一个 diff 组件
+func PreviewRollback(c echo.Context, configID, targetHistoryID uint) (resp view.RespDiffConfig, err error) {
+	var configuration db.Configuration
+	if err = mysql.Where("id = ?", configID).First(&configuration).Error; err != nil {
+		return
+	}
+
+	appInfo, err := resource.Resource.GetApp(int(configuration.AID))
+	if err != nil {
+		return
+	}
+	if _, err = requireAction(c, ActionRead, appInfo.AppName, configuration.Env, configuration.Zone); err != nil {
+		return
+	}
+
+	var target db.ConfigurationHistory
+	if err = mysql.Preload("User").Where("id = ? and configuration_id = ?", targetHistoryID, configID).First(&target).Error; err != nil {
+		return
+	}
+
+	resp.Origin = &view.RespDetailConfig{
+		ID:      target.ID,
+		AID:     configuration.AID,
+		Name:    configuration.Name,
+		Content: target.Content,
+		Format:  configuration.Format,
+		Env:     configuration.Env,
+		Zone:    configuration.Zone,
+	}
+
+	resp.Modified = view.RespDetailConfig{
+		ID:        configuration.ID,
+		AID:       configuration.AID,
+		Name:      configuration.Name,
+		Content:   configuration.Content,
+		Format:    configuration.Format,
+		Env:       configuration.Env,
+		Zone:      configuration.Zone,
+		UpdatedAt: configuration.UpdatedAt,
+	}
+
+	return
+}
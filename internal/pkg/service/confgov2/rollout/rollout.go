@@ -0,0 +1,316 @@
+// Package rollout 实现配置发布的灰度（分批）推进：按批次写入 etcd，轮询生效状态达标后再进入下一批，
+// 支持手动暂停/恢复/中止，以及生效比例回退时自动中止并回滚。
+package rollout
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/douyu/juno/internal/pkg/service/appevent"
+	"github.com/douyu/jupiter/pkg/xlog"
+	"go.uber.org/zap"
+)
+
+// State 描述一次灰度发布的整体状态
+type State string
+
+const (
+	StateRunning State = "running"
+	StatePaused  State = "paused"
+	StateAborted State = "aborted"
+	StateDone    State = "done"
+)
+
+// Wave 描述灰度发布的一个批次：Hosts 非空时使用显式 Hosts，否则 Percent>0 时按百分比圈定范围；
+// MinBake 是该批次达到健康比例后，进入下一批前的最短观察（烘焙）时长
+type Wave struct {
+	Percent int           `json:"percent"`
+	Hosts   []string      `json:"hosts,omitempty"`
+	MinBake time.Duration `json:"min_bake"`
+}
+
+// PublishFunc 把某一批次的配置写入 etcd，由调用方（confgov2）提供，避免反向依赖
+type PublishFunc func(hosts []string) error
+
+// StatusFunc 返回某一批次当前 TakeEffect 成功的比例，取值 [0,1]
+type StatusFunc func(hosts []string) (successRatio float64, err error)
+
+// RollbackFunc 在灰度被中止时，把 etcd 回退到发布前的版本
+type RollbackFunc func() error
+
+// RecordFunc 在某一批次 waitHealthy 通过、真正被判定为"已推进"之后调用，由调用方对这一批 hosts
+// 做推进后的记录（confgov2 在这里广播发布事件）；返回的 error 只记日志，不会中止灰度
+type RecordFunc func(hosts []string) error
+
+// Request 是一次灰度发布所需的全部依赖，由 confgov2.Publish 组装
+type Request struct {
+	ConfigurationID uint
+	AllHosts        []string
+	Waves           []Wave
+	MinSuccessRatio float64 // 默认 1.0，要求 TakeEffect 的比例达到该值才进入下一批
+	PollInterval    time.Duration
+
+	Publish  PublishFunc
+	Status   StatusFunc
+	Rollback RollbackFunc
+	Record   RecordFunc
+
+	// OnTransition 在每次批次切换/状态变化时回调，供调用方把 wave 状态落库（ConfigurationRollout）
+	OnTransition func(waveIndex int, state State, message string)
+}
+
+// Status 是 GetRolloutStatus 对外暴露的快照
+type Status struct {
+	ConfigurationID uint   `json:"configuration_id"`
+	State           State  `json:"state"`
+	WaveIndex       int    `json:"wave_index"`
+	WaveCount       int    `json:"wave_count"`
+	Message         string `json:"message,omitempty"`
+}
+
+type rolloutTask struct {
+	mu        sync.Mutex
+	req       Request
+	waveIndex int
+	state     State
+	message   string
+
+	pause  chan struct{}
+	resume chan struct{}
+	abort  chan struct{}
+}
+
+var (
+	registryMu sync.Mutex
+	registry   = make(map[uint]*rolloutTask)
+)
+
+// Start 启动一次灰度发布的后台 worker；返回的 error 只代表入参是否合法，
+// 批次推进状态通过 GetRolloutStatus 查询
+func Start(ctx context.Context, req Request) error {
+	if len(req.Waves) == 0 {
+		return fmt.Errorf("rollout: waves 不能为空")
+	}
+	if req.MinSuccessRatio <= 0 {
+		req.MinSuccessRatio = 1.0
+	}
+	if req.PollInterval <= 0 {
+		req.PollInterval = 5 * time.Second
+	}
+
+	t := &rolloutTask{
+		req:    req,
+		state:  StateRunning,
+		pause:  make(chan struct{}, 1),
+		resume: make(chan struct{}, 1),
+		abort:  make(chan struct{}, 1),
+	}
+
+	registryMu.Lock()
+	registry[req.ConfigurationID] = t
+	registryMu.Unlock()
+
+	go t.run(ctx)
+
+	return nil
+}
+
+func (t *rolloutTask) run(ctx context.Context) {
+	for idx, wave := range t.req.Waves {
+		t.mu.Lock()
+		t.waveIndex = idx
+		t.mu.Unlock()
+		t.notify()
+
+		hosts := wave.Hosts
+		if wave.Percent > 0 && len(hosts) == 0 {
+			hosts = pickByPercent(t.req.AllHosts, wave.Percent)
+		}
+
+		if err := t.req.Publish(hosts); err != nil {
+			t.fail(fmt.Sprintf("第%d批写入etcd失败: %s", idx+1, err.Error()))
+			return
+		}
+		appevent.AppEvent.ConfgoFileRolloutWaveEvent(t.req.ConfigurationID, idx, len(t.req.Waves), "applied")
+
+		if !t.waitHealthy(ctx, wave, hosts) {
+			return
+		}
+		if t.req.Record != nil {
+			if err := t.req.Record(hosts); err != nil {
+				xlog.Error("rollout.record", xlog.String("error", err.Error()))
+			}
+		}
+		appevent.AppEvent.ConfgoFileRolloutWaveEvent(t.req.ConfigurationID, idx, len(t.req.Waves), "promoted")
+	}
+
+	t.mu.Lock()
+	t.state = StateDone
+	t.mu.Unlock()
+	t.notify()
+}
+
+func (t *rolloutTask) notify() {
+	if t.req.OnTransition == nil {
+		return
+	}
+	t.mu.Lock()
+	waveIndex, state, message := t.waveIndex, t.state, t.message
+	t.mu.Unlock()
+	t.req.OnTransition(waveIndex, state, message)
+}
+
+// waitHealthy 轮询批次的生效比例，达标后再 bake（观察）MinBake 时长；期间响应 pause/resume/abort，
+// 若已经健康过又回退到阈值以下，视为发布异常，自动中止并回滚
+func (t *rolloutTask) waitHealthy(ctx context.Context, wave Wave, hosts []string) bool {
+	ticker := time.NewTicker(t.req.PollInterval)
+	defer ticker.Stop()
+
+	var healthySince time.Time
+
+	for {
+		select {
+		case <-ctx.Done():
+			t.fail("rollout: context 已取消")
+			return false
+		case <-t.abort:
+			t.rollbackAndMark("已手动中止")
+			return false
+		case <-t.pause:
+			if !t.waitResumeOrAbort(ctx) {
+				return false
+			}
+		case <-ticker.C:
+			ratio, err := t.req.Status(hosts)
+			if err != nil {
+				xlog.Error("rollout.waitHealthy", xlog.String("error", err.Error()))
+				continue
+			}
+
+			if ratio < t.req.MinSuccessRatio {
+				if !healthySince.IsZero() {
+					t.rollbackAndMark(fmt.Sprintf("生效比例回退至%.2f，自动中止", ratio))
+					return false
+				}
+				continue
+			}
+
+			if healthySince.IsZero() {
+				healthySince = time.Now()
+			}
+			if time.Since(healthySince) >= wave.MinBake {
+				return true
+			}
+		}
+	}
+}
+
+func (t *rolloutTask) waitResumeOrAbort(ctx context.Context) bool {
+	t.mu.Lock()
+	t.state = StatePaused
+	t.mu.Unlock()
+	t.notify()
+
+	select {
+	case <-t.resume:
+		t.mu.Lock()
+		t.state = StateRunning
+		t.mu.Unlock()
+		t.notify()
+		return true
+	case <-t.abort:
+		t.rollbackAndMark("暂停期间被中止")
+		return false
+	case <-ctx.Done():
+		t.fail("rollout: context 已取消")
+		return false
+	}
+}
+
+func (t *rolloutTask) rollbackAndMark(reason string) {
+	if t.req.Rollback != nil {
+		if err := t.req.Rollback(); err != nil {
+			reason = reason + "; 回滚失败: " + err.Error()
+		}
+	}
+	t.fail(reason)
+}
+
+func (t *rolloutTask) fail(reason string) {
+	t.mu.Lock()
+	t.state = StateAborted
+	t.message = reason
+	t.mu.Unlock()
+	xlog.Error("rollout.fail", zap.Uint("configuration_id", t.req.ConfigurationID), xlog.String("reason", reason))
+	t.notify()
+}
+
+func pickByPercent(hosts []string, percent int) []string {
+	if percent >= 100 || len(hosts) == 0 {
+		return hosts
+	}
+	n := len(hosts) * percent / 100
+	if n == 0 {
+		n = 1
+	}
+	return hosts[:n]
+}
+
+// PauseRollout 暂停指定 configurationID 正在进行中的灰度发布
+func PauseRollout(configurationID uint) error {
+	t, err := lookup(configurationID)
+	if err != nil {
+		return err
+	}
+	t.pause <- struct{}{}
+	return nil
+}
+
+// ResumeRollout 恢复一个已暂停的灰度发布
+func ResumeRollout(configurationID uint) error {
+	t, err := lookup(configurationID)
+	if err != nil {
+		return err
+	}
+	t.resume <- struct{}{}
+	return nil
+}
+
+// AbortRollout 中止一个灰度发布，已写入的批次会被回滚
+func AbortRollout(configurationID uint) error {
+	t, err := lookup(configurationID)
+	if err != nil {
+		return err
+	}
+	t.abort <- struct{}{}
+	return nil
+}
+
+// GetRolloutStatus 返回灰度发布的当前状态快照
+func GetRolloutStatus(configurationID uint) (Status, error) {
+	t, err := lookup(configurationID)
+	if err != nil {
+		return Status{}, err
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return Status{
+		ConfigurationID: configurationID,
+		State:           t.state,
+		WaveIndex:       t.waveIndex,
+		WaveCount:       len(t.req.Waves),
+		Message:         t.message,
+	}, nil
+}
+
+func lookup(configurationID uint) (*rolloutTask, error) {
+	registryMu.Lock()
+	defer registryMu.Unlock()
+	t, ok := registry[configurationID]
+	if !ok {
+		return nil, fmt.Errorf("rollout: 未找到configuration_id=%d的灰度任务", configurationID)
+	}
+	return t, nil
+}
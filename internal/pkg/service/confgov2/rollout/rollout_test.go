@@ -0,0 +1,34 @@
+package rollout
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestPickByPercent(t *testing.T) {
+	hosts := []string{"h1", "h2", "h3", "h4", "h5"}
+
+	cases := []struct {
+		name    string
+		hosts   []string
+		percent int
+		want    []string
+	}{
+		{"0%取不到任何host时至少保底1个", hosts, 0, []string{"h1"}},
+		{"100%返回全部", hosts, 100, hosts},
+		{"超过100%返回全部", hosts, 150, hosts},
+		{"50%向下取整", hosts, 50, []string{"h1", "h2"}},
+		{"取整后为0时保底1个", hosts, 10, []string{"h1"}},
+		{"空列表直接返回空", nil, 50, nil},
+		{"单host列表", []string{"only"}, 1, []string{"only"}},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			got := pickByPercent(c.hosts, c.percent)
+			if !reflect.DeepEqual(got, c.want) {
+				t.Errorf("pickByPercent(%v, %d) = %v, want %v", c.hosts, c.percent, got, c.want)
+			}
+		})
+	}
+}
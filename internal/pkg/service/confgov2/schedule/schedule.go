@@ -0,0 +1,230 @@
+// Package schedule 提供定时/周期发布的纯逻辑部分：解析一次性时间戳或 cron 表达式、判断黑名单窗口期，
+// 以及驱动发布的轮询循环。数据库访问（claim/ack）和实际发布动作由调用方（confgov2）注入，
+// 避免这个包直接依赖 gorm/mysql。
+package schedule
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/robfig/cron/v3"
+)
+
+// ErrBlackoutSkip 标记一次因黑名单窗口期被跳过的触发；这不是发布失败，调用方应当保留任务的
+// pending 状态和已经算好的 next_run_at，等待下一次触发，而不是把任务标记为 failed
+var ErrBlackoutSkip = errors.New("处于黑名单窗口期，本次发布已跳过")
+
+// State 是一个定时任务当前所处的状态
+type State string
+
+const (
+	StatePending   State = "pending"
+	StateRunning   State = "running"
+	StatePaused    State = "paused"
+	StateCancelled State = "cancelled"
+	StateDone      State = "done"
+	StateFailed    State = "failed"
+)
+
+// Blackout 描述一个禁止发布的窗口期，比如 "周五18:00 到 周一09:00，仅限 env=prod"；
+// Env 为空表示对所有环境生效
+type Blackout struct {
+	StartWeekday time.Weekday `json:"start_weekday"`
+	StartTime    string       `json:"start_time"` // "HH:MM"
+	EndWeekday   time.Weekday `json:"end_weekday"`
+	EndTime      string       `json:"end_time"`
+	Env          string       `json:"env,omitempty"`
+}
+
+// Spec 是一次定时发布的调度规则：At 和 Cron 二选一
+type Spec struct {
+	At        *time.Time `json:"at,omitempty"`
+	Cron      string     `json:"cron,omitempty"`
+	EndAt     *time.Time `json:"end_at,omitempty"`
+	Blackouts []Blackout `json:"blackouts,omitempty"`
+}
+
+// Validate 校验 Spec 本身是否合法（at/cron 二选一，cron 表达式可解析）
+func (s Spec) Validate() error {
+	if s.At == nil && s.Cron == "" {
+		return fmt.Errorf("必须指定一次性发布时间 at 或 cron 表达式")
+	}
+	if s.At != nil && s.Cron != "" {
+		return fmt.Errorf("at 和 cron 不能同时指定")
+	}
+	if s.Cron != "" {
+		if _, err := cron.ParseStandard(s.Cron); err != nil {
+			return fmt.Errorf("cron 表达式不合法: %w", err)
+		}
+	}
+	return nil
+}
+
+// NextRun 计算下一次触发时间；一次性任务触发过之后返回 zero time 表示不再触发
+func (s Spec) NextRun(after time.Time) (time.Time, bool) {
+	if s.EndAt != nil && after.After(*s.EndAt) {
+		return time.Time{}, false
+	}
+
+	if s.At != nil {
+		if after.After(*s.At) {
+			return time.Time{}, false
+		}
+		return *s.At, true
+	}
+
+	schedule, err := cron.ParseStandard(s.Cron)
+	if err != nil {
+		return time.Time{}, false
+	}
+	next := schedule.Next(after)
+	if s.EndAt != nil && next.After(*s.EndAt) {
+		return time.Time{}, false
+	}
+	return next, true
+}
+
+// InBlackout 判断 env 在时刻 t 是否处于任意一个黑名单窗口期内
+func (s Spec) InBlackout(t time.Time, env string) bool {
+	for _, b := range s.Blackouts {
+		if b.Env != "" && b.Env != env {
+			continue
+		}
+		if blackoutContains(b, t) {
+			return true
+		}
+	}
+	return false
+}
+
+// blackoutContains 把一周按分钟展开成 [0, 7*24*60) 的环形区间来判断 t 是否落在 [start, end) 内，
+// 支持跨周末的窗口（比如周五到下周一）
+func blackoutContains(b Blackout, t time.Time) bool {
+	start, err := minuteOfWeek(b.StartWeekday, b.StartTime)
+	if err != nil {
+		return false
+	}
+	end, err := minuteOfWeek(b.EndWeekday, b.EndTime)
+	if err != nil {
+		return false
+	}
+	now := int(t.Weekday())*24*60 + t.Hour()*60 + t.Minute()
+
+	if start <= end {
+		return now >= start && now < end
+	}
+	// 跨周界，例如周五18:00 到 周一09:00
+	return now >= start || now < end
+}
+
+func minuteOfWeek(weekday time.Weekday, clock string) (int, error) {
+	var hour, minute int
+	if _, err := fmt.Sscanf(clock, "%d:%d", &hour, &minute); err != nil {
+		return 0, fmt.Errorf("非法的时间格式 %q，应为 HH:MM", clock)
+	}
+	return int(weekday)*24*60 + hour*60 + minute, nil
+}
+
+// BlackoutEnd 返回包含时刻 t 的黑名单窗口的结束时间；黑名单只是推迟触发，不是丢弃——一次性任务命中
+// 窗口期之后，调用方应该改到这个时间点重试，而不是直接当成任务已完成
+func (s Spec) BlackoutEnd(t time.Time, env string) (time.Time, bool) {
+	for _, b := range s.Blackouts {
+		if b.Env != "" && b.Env != env {
+			continue
+		}
+		if end, ok := blackoutEndAfter(b, t); ok {
+			return end, true
+		}
+	}
+	return time.Time{}, false
+}
+
+// blackoutEndAfter 把 EndWeekday/EndTime 换算回 t 所在周（必要时下一周）里第一个晚于 t 的绝对时间
+func blackoutEndAfter(b Blackout, t time.Time) (time.Time, bool) {
+	if !blackoutContains(b, t) {
+		return time.Time{}, false
+	}
+
+	var endHour, endMinute int
+	if _, err := fmt.Sscanf(b.EndTime, "%d:%d", &endHour, &endMinute); err != nil {
+		return time.Time{}, false
+	}
+
+	midnight := time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, t.Location())
+	weekStart := midnight.AddDate(0, 0, -int(midnight.Weekday()))
+
+	for week := 0; week < 2; week++ {
+		day := weekStart.AddDate(0, 0, int(b.EndWeekday)+7*week)
+		candidate := time.Date(day.Year(), day.Month(), day.Day(), endHour, endMinute, 0, 0, day.Location())
+		if candidate.After(t) {
+			return candidate, true
+		}
+	}
+	return time.Time{}, false
+}
+
+// Job 是调度器需要驱动的一个定时发布任务
+type Job struct {
+	ID              uint
+	ConfigurationID uint
+	HistoryVersion  string
+	Env             string
+	Spec            Spec
+}
+
+// ClaimFunc 用 `FOR UPDATE SKIP LOCKED` 之类的方式抢占到期任务，多个 juno-admin 节点同时轮询也不会重复执行
+type ClaimFunc func(ctx context.Context, now time.Time) ([]Job, error)
+
+// PublishFunc 执行一次实际发布
+type PublishFunc func(ctx context.Context, job Job) error
+
+// AckFunc 记录一次任务执行的结果，并按 Spec 计算好下一次 next_run_at（一次性任务则标记为完成）
+type AckFunc func(ctx context.Context, job Job, runErr error)
+
+// Scheduler 是跑在 juno-admin 进程里的单例轮询器
+type Scheduler struct {
+	claim        ClaimFunc
+	publish      PublishFunc
+	ack          AckFunc
+	pollInterval time.Duration
+}
+
+// NewScheduler 组装一个调度器；claim/publish/ack 由 confgov2 注入，分别对应数据库抢占、实际发布、落库确认
+func NewScheduler(claim ClaimFunc, publish PublishFunc, ack AckFunc, pollInterval time.Duration) *Scheduler {
+	if pollInterval <= 0 {
+		pollInterval = 30 * time.Second
+	}
+	return &Scheduler{claim: claim, publish: publish, ack: ack, pollInterval: pollInterval}
+}
+
+// Run 是阻塞调用，按 pollInterval 轮询到期任务直到 ctx 被取消；应当在进程内只启动一次
+func (s *Scheduler) Run(ctx context.Context) {
+	ticker := time.NewTicker(s.pollInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			s.tick(ctx)
+		}
+	}
+}
+
+func (s *Scheduler) tick(ctx context.Context) {
+	jobs, err := s.claim(ctx, time.Now())
+	if err != nil {
+		return
+	}
+	for _, job := range jobs {
+		if job.Spec.InBlackout(time.Now(), job.Env) {
+			s.ack(ctx, job, ErrBlackoutSkip)
+			continue
+		}
+		err := s.publish(ctx, job)
+		s.ack(ctx, job, err)
+	}
+}
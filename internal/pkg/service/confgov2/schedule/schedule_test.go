@@ -0,0 +1,90 @@
+package schedule
+
+import (
+	"testing"
+	"time"
+)
+
+func TestBlackoutContains(t *testing.T) {
+	// 周五18:00 到 周一09:00，覆盖周末，用来验证跨周界的环形区间判断
+	weekend := Blackout{
+		StartWeekday: time.Friday,
+		StartTime:    "18:00",
+		EndWeekday:   time.Monday,
+		EndTime:      "09:00",
+	}
+	// 周二10:00 到 周二12:00，不跨周界
+	weekday := Blackout{
+		StartWeekday: time.Tuesday,
+		StartTime:    "10:00",
+		EndWeekday:   time.Tuesday,
+		EndTime:      "12:00",
+	}
+
+	cases := []struct {
+		name string
+		b    Blackout
+		at   time.Time
+		want bool
+	}{
+		{"周五窗口开始前", weekend, date(2026, 7, 24, 17, 59), false},
+		{"周五窗口开始时刻", weekend, date(2026, 7, 24, 18, 0), true},
+		{"周六在窗口内", weekend, date(2026, 7, 25, 12, 0), true},
+		{"周一窗口结束前", weekend, date(2026, 7, 27, 8, 59), true},
+		{"周一窗口结束时刻", weekend, date(2026, 7, 27, 9, 0), false},
+		{"不跨周界-窗口前", weekday, date(2026, 7, 21, 9, 59), false},
+		{"不跨周界-窗口内", weekday, date(2026, 7, 21, 11, 0), true},
+		{"不跨周界-窗口后", weekday, date(2026, 7, 21, 12, 0), false},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			if got := blackoutContains(c.b, c.at); got != c.want {
+				t.Errorf("blackoutContains(%v) = %v, want %v", c.at, got, c.want)
+			}
+		})
+	}
+}
+
+func TestMinuteOfWeek(t *testing.T) {
+	// 与 time.Weekday 一致，一周从周日 00:00（第 0 分钟）开始计
+	got, err := minuteOfWeek(time.Monday, "00:00")
+	if err != nil || got != 24*60 {
+		t.Fatalf("minuteOfWeek(Monday, 00:00) = %d, %v, want %d, nil", got, err, 24*60)
+	}
+
+	got, err = minuteOfWeek(time.Tuesday, "01:30")
+	if err != nil || got != 2*24*60+90 {
+		t.Fatalf("minuteOfWeek(Tuesday, 01:30) = %d, %v, want %d, nil", got, err, 2*24*60+90)
+	}
+
+	if _, err := minuteOfWeek(time.Monday, "not-a-time"); err == nil {
+		t.Fatal("minuteOfWeek with malformed clock should error")
+	}
+}
+
+func TestBlackoutEndAfter(t *testing.T) {
+	weekend := Blackout{
+		StartWeekday: time.Friday,
+		StartTime:    "18:00",
+		EndWeekday:   time.Monday,
+		EndTime:      "09:00",
+	}
+
+	end, ok := blackoutEndAfter(weekend, date(2026, 7, 25, 12, 0))
+	if !ok {
+		t.Fatal("expected t to be inside the blackout window")
+	}
+	want := date(2026, 7, 27, 9, 0)
+	if !end.Equal(want) {
+		t.Errorf("blackoutEndAfter = %v, want %v", end, want)
+	}
+
+	if _, ok := blackoutEndAfter(weekend, date(2026, 7, 22, 12, 0)); ok {
+		t.Error("blackoutEndAfter should report false outside the window")
+	}
+}
+
+func date(year int, month time.Month, day, hour, minute int) time.Time {
+	return time.Date(year, month, day, hour, minute, 0, 0, time.UTC)
+}
@@ -0,0 +1,288 @@
+package confgov2
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/douyu/juno/internal/pkg/service/confgov2/schedule"
+	"github.com/douyu/juno/internal/pkg/service/resource"
+	"github.com/douyu/juno/pkg/model/db"
+	"github.com/douyu/juno/pkg/model/view"
+	"github.com/douyu/jupiter/pkg/xlog"
+	"github.com/labstack/echo/v4"
+)
+
+// SchedulePublish 注册一次定时/周期发布任务：spec 可以是一次性 RFC3339 时间戳，也可以是 cron 表达式加可选
+// 截止时间和黑名单窗口期。创建任务本身是一个独立的 RBAC action，不复用 publish_nonprod/publish_prod
+func SchedulePublish(c echo.Context, param view.ReqSchedulePublish, user *db.User) (id uint, err error) {
+	var configuration db.Configuration
+	if err = mysql.Where("id = ?", param.ConfigurationID).First(&configuration).Error; err != nil {
+		return
+	}
+
+	appInfo, err := resource.Resource.GetApp(int(configuration.AID))
+	if err != nil {
+		return
+	}
+
+	operator, err := requireAction(c, ActionSchedule, appInfo.AppName, configuration.Env, configuration.Zone)
+	if err != nil {
+		return
+	}
+
+	// 定时发布最终也是走 doPublish 发到 etcd，生产环境一样需要一个独立的审批人；调度器真正执行的
+	// 时候没有实时请求可以再弹出审批流程，所以审批人必须在创建任务时就确定并校验过
+	if isProdZone(configuration.Env) {
+		if err = requireApprover(param.ApproverUID, operator, appInfo.AppName, configuration.Env, configuration.Zone); err != nil {
+			return
+		}
+	}
+
+	spec := schedule.Spec{
+		At:        param.At,
+		Cron:      param.Cron,
+		EndAt:     param.EndAt,
+		Blackouts: toScheduleBlackouts(param.Blackouts),
+	}
+	if err = spec.Validate(); err != nil {
+		return
+	}
+
+	if spec.InBlackout(time.Now(), configuration.Env) {
+		perms, permErr := effectivePermissions(operator, appInfo.AppName, configuration.Env, configuration.Zone)
+		if permErr != nil {
+			err = permErr
+			return
+		}
+		if !perms[ActionOverrideBlackout] {
+			err = fmt.Errorf("当前处于黑名单窗口期，禁止创建定时发布任务")
+			return
+		}
+	}
+
+	nextRun, ok := spec.NextRun(time.Now())
+	if !ok {
+		err = fmt.Errorf("调度规则不会产生任何触发时间")
+		return
+	}
+
+	specJSON, err := json.Marshal(spec)
+	if err != nil {
+		return
+	}
+
+	row := db.ConfigurationSchedule{
+		ConfigurationID: param.ConfigurationID,
+		HistoryVersion:  param.HistoryVersion,
+		Spec:            string(specJSON),
+		State:           string(schedule.StatePending),
+		NextRunAt:       nextRun,
+		CreatedByUID:    uint(user.Uid),
+		ApproverUID:     param.ApproverUID,
+	}
+	if err = mysql.Save(&row).Error; err != nil {
+		return
+	}
+
+	id = row.ID
+	return
+}
+
+// ListScheduled 列出某个配置下所有未取消的定时发布任务
+func ListScheduled(c echo.Context, configID uint) (list []db.ConfigurationSchedule, err error) {
+	if err = requireScheduleAction(c, ActionRead, configID); err != nil {
+		return
+	}
+	err = mysql.Where("configuration_id = ? and state != ?", configID, string(schedule.StateCancelled)).
+		Order("id desc").Find(&list).Error
+	return
+}
+
+// CancelScheduled 取消一个尚未执行的定时发布任务
+func CancelScheduled(c echo.Context, scheduleID uint) error {
+	var row db.ConfigurationSchedule
+	if err := mysql.Where("id = ?", scheduleID).First(&row).Error; err != nil {
+		return err
+	}
+	if err := requireScheduleAction(c, ActionSchedule, row.ConfigurationID); err != nil {
+		return err
+	}
+	return mysql.Model(&db.ConfigurationSchedule{}).Where("id = ?", scheduleID).
+		Update("state", string(schedule.StateCancelled)).Error
+}
+
+// PauseScheduled 暂停一个待执行的定时发布任务，调度器不会再为其计算下一次触发
+func PauseScheduled(c echo.Context, scheduleID uint) error {
+	var row db.ConfigurationSchedule
+	if err := mysql.Where("id = ?", scheduleID).First(&row).Error; err != nil {
+		return err
+	}
+	if err := requireScheduleAction(c, ActionSchedule, row.ConfigurationID); err != nil {
+		return err
+	}
+	return mysql.Model(&db.ConfigurationSchedule{}).Where("id = ?", scheduleID).
+		Update("state", string(schedule.StatePaused)).Error
+}
+
+func requireScheduleAction(c echo.Context, action Action, configID uint) error {
+	var configuration db.Configuration
+	if err := mysql.Where("id = ?", configID).First(&configuration).Error; err != nil {
+		return err
+	}
+	appInfo, err := resource.Resource.GetApp(int(configuration.AID))
+	if err != nil {
+		return err
+	}
+	_, err = requireAction(c, action, appInfo.AppName, configuration.Env, configuration.Zone)
+	return err
+}
+
+func toScheduleBlackouts(in []view.ReqScheduleBlackout) []schedule.Blackout {
+	out := make([]schedule.Blackout, 0, len(in))
+	for _, b := range in {
+		out = append(out, schedule.Blackout{
+			StartWeekday: time.Weekday(b.StartWeekday),
+			StartTime:    b.StartTime,
+			EndWeekday:   time.Weekday(b.EndWeekday),
+			EndTime:      b.EndTime,
+			Env:          b.Env,
+		})
+	}
+	return out
+}
+
+var startSchedulerOnce sync.Once
+
+// StartScheduler 启动进程内单例的定时发布调度器；多个 juno-admin 节点都可以调用，
+// claimDueSchedules 用 `FOR UPDATE SKIP LOCKED` 抢占任务，保证同一个任务不会被多个节点重复执行
+func StartScheduler(ctx context.Context) {
+	startSchedulerOnce.Do(func() {
+		scheduler := schedule.NewScheduler(claimDueSchedules, publishScheduledJob, ackScheduledJob, 30*time.Second)
+		go scheduler.Run(ctx)
+	})
+}
+
+func claimDueSchedules(ctx context.Context, now time.Time) ([]schedule.Job, error) {
+	var rows []db.ConfigurationSchedule
+
+	tx := mysql.Begin()
+	err := tx.Raw(
+		"SELECT * FROM configuration_schedule WHERE state = ? AND next_run_at <= ? FOR UPDATE SKIP LOCKED",
+		string(schedule.StatePending), now,
+	).Scan(&rows).Error
+	if err != nil {
+		tx.Rollback()
+		return nil, err
+	}
+
+	jobs := make([]schedule.Job, 0, len(rows))
+	for _, row := range rows {
+		if err := tx.Model(&db.ConfigurationSchedule{}).Where("id = ?", row.ID).
+			Update("state", string(schedule.StateRunning)).Error; err != nil {
+			tx.Rollback()
+			return nil, err
+		}
+
+		var spec schedule.Spec
+		if err := json.Unmarshal([]byte(row.Spec), &spec); err != nil {
+			xlog.Error("claimDueSchedules", xlog.String("error", "invalid spec json: "+err.Error()))
+			continue
+		}
+
+		var configuration db.Configuration
+		env := ""
+		if tx.Where("id = ?", row.ConfigurationID).First(&configuration).Error == nil {
+			env = configuration.Env
+		}
+
+		jobs = append(jobs, schedule.Job{
+			ID:              row.ID,
+			ConfigurationID: row.ConfigurationID,
+			HistoryVersion:  row.HistoryVersion,
+			Env:             env,
+			Spec:            spec,
+		})
+	}
+
+	if err := tx.Commit().Error; err != nil {
+		tx.Rollback()
+		return nil, err
+	}
+	return jobs, nil
+}
+
+func publishScheduledJob(ctx context.Context, job schedule.Job) error {
+	var row db.ConfigurationSchedule
+	if err := mysql.Where("id = ?", job.ID).First(&row).Error; err != nil {
+		return err
+	}
+
+	var configuration db.Configuration
+	if err := mysql.Where("id = ?", job.ConfigurationID).First(&configuration).Error; err != nil {
+		return err
+	}
+	appInfo, err := resource.Resource.GetApp(int(configuration.AID))
+	if err != nil {
+		return err
+	}
+
+	creator := caller{UID: row.CreatedByUID}
+
+	// 调度器执行时没有实时请求，生产环境的审批人要求在 SchedulePublish 创建时已经校验过一次，
+	// 这里执行前再校验一次，防止审批人在任务等待期间被收回 approve 权限，绕开 chunk0-1 的生产发布审批
+	if isProdZone(configuration.Env) {
+		if err := requireApprover(row.ApproverUID, creator, appInfo.AppName, configuration.Env, configuration.Zone); err != nil {
+			return err
+		}
+	}
+
+	var user db.User
+	if err := mysql.Where("uid = ?", row.CreatedByUID).First(&user).Error; err != nil {
+		return err
+	}
+
+	return doPublish(creator, configuration, appInfo, view.ReqPublishConfig{
+		ID:          job.ConfigurationID,
+		Version:     job.HistoryVersion,
+		ApproverUID: row.ApproverUID,
+	}, &user)
+}
+
+func ackScheduledJob(ctx context.Context, job schedule.Job, runErr error) {
+	updates := map[string]interface{}{}
+
+	now := time.Now()
+	next, ok := job.Spec.NextRun(now)
+	switch {
+	case ok:
+		updates["state"] = string(schedule.StatePending)
+		updates["next_run_at"] = next
+	case errors.Is(runErr, schedule.ErrBlackoutSkip) && job.Spec.At != nil:
+		// 一次性任务命中黑名单窗口期：At 已经在过去，NextRun 会判定任务"过期"，但黑名单只是推迟发布，
+		// 不是丢弃，改到窗口结束后立刻重试，而不是直接标记完成
+		if end, inBlackout := job.Spec.BlackoutEnd(now, job.Env); inBlackout {
+			updates["state"] = string(schedule.StatePending)
+			updates["next_run_at"] = end
+		} else {
+			updates["state"] = string(schedule.StateDone)
+		}
+	default:
+		updates["state"] = string(schedule.StateDone)
+	}
+
+	switch {
+	case errors.Is(runErr, schedule.ErrBlackoutSkip):
+		// 黑名单窗口期只是跳过本次触发，沿用上面算好的 pending/next_run_at，不计入失败，下次触发时间到了还会重试
+		updates["last_error"] = runErr.Error()
+	case runErr != nil:
+		updates["state"] = string(schedule.StateFailed)
+		updates["last_error"] = runErr.Error()
+		xlog.Error("ackScheduledJob", xlog.String("error", runErr.Error()))
+	}
+
+	mysql.Model(&db.ConfigurationSchedule{}).Where("id = ?", job.ID).Updates(updates)
+}
@@ -0,0 +1,60 @@
+package snapshot
+
+import "fmt"
+
+// Mode 控制 ImportSnapshot 对冲突的处理方式
+type Mode string
+
+const (
+	ModeDryRun    Mode = "dry-run"
+	ModeCreate    Mode = "create"
+	ModeOverwrite Mode = "overwrite"
+	ModeMerge     Mode = "merge"
+)
+
+// ConfigPlan 是单个配置文件的导入计划
+type ConfigPlan struct {
+	Name          string   `json:"name"`
+	WillCreate    bool     `json:"will_create"`
+	Conflict      bool     `json:"conflict"`
+	HistoryToAdd  []string `json:"history_to_add"`
+	HistoryToSkip []string `json:"history_to_skip"`
+}
+
+// Plan 是 ImportSnapshot 在 dry-run 模式下返回的完整导入计划，create/overwrite/merge 模式下也会
+// 先算出同样的 Plan 再执行，方便调用方记录“实际做了什么”
+type Plan struct {
+	Mode    Mode         `json:"mode"`
+	Configs []ConfigPlan `json:"configs"`
+}
+
+// ExistingLookup 由调用方注入：按配置名查询目标环境下是否已存在同名配置，以及它已有哪些历史版本号
+type ExistingLookup func(name string) (exists bool, historyVersions map[string]bool)
+
+// PlanImport 根据 Bundle 和目标环境现状算出一份导入计划；create 模式下任何冲突都视为失败
+func PlanImport(b Bundle, mode Mode, lookup ExistingLookup) (Plan, error) {
+	plan := Plan{Mode: mode}
+
+	for _, cfg := range b.Configs {
+		exists, historyVersions := lookup(cfg.Configuration.Name)
+
+		cp := ConfigPlan{Name: cfg.Configuration.Name, WillCreate: !exists, Conflict: exists}
+
+		for _, h := range cfg.History {
+			// overwrite 会先清掉目标已有的历史再整体写入，快照里的每个版本都会被重新写入
+			if historyVersions[h.Version] && mode != ModeOverwrite {
+				cp.HistoryToSkip = append(cp.HistoryToSkip, h.Version)
+			} else {
+				cp.HistoryToAdd = append(cp.HistoryToAdd, h.Version)
+			}
+		}
+
+		if mode == ModeCreate && cp.Conflict {
+			return plan, fmt.Errorf("配置 %s 已存在，create 模式下禁止导入", cfg.Configuration.Name)
+		}
+
+		plan.Configs = append(plan.Configs, cp)
+	}
+
+	return plan, nil
+}
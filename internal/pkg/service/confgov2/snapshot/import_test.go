@@ -0,0 +1,72 @@
+package snapshot
+
+import (
+	"reflect"
+	"testing"
+)
+
+func makeBundle() Bundle {
+	return Bundle{
+		Configs: []ConfigSnapshot{
+			{
+				Configuration: ConfigurationMeta{Name: "app.toml"},
+				History: []HistoryEntry{
+					{Version: "v1"},
+					{Version: "v2"},
+				},
+			},
+		},
+	}
+}
+
+func TestPlanImportCreate(t *testing.T) {
+	lookup := func(name string) (bool, map[string]bool) { return false, nil }
+
+	plan, err := PlanImport(makeBundle(), ModeCreate, lookup)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	want := ConfigPlan{Name: "app.toml", WillCreate: true, Conflict: false, HistoryToAdd: []string{"v1", "v2"}}
+	if !reflect.DeepEqual(plan.Configs[0], want) {
+		t.Errorf("PlanImport() = %+v, want %+v", plan.Configs[0], want)
+	}
+}
+
+func TestPlanImportCreateConflict(t *testing.T) {
+	lookup := func(name string) (bool, map[string]bool) { return true, map[string]bool{"v1": true} }
+
+	if _, err := PlanImport(makeBundle(), ModeCreate, lookup); err == nil {
+		t.Fatal("create mode should fail when the config already exists")
+	}
+}
+
+func TestPlanImportMerge(t *testing.T) {
+	lookup := func(name string) (bool, map[string]bool) { return true, map[string]bool{"v1": true} }
+
+	plan, err := PlanImport(makeBundle(), ModeMerge, lookup)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	want := ConfigPlan{
+		Name:          "app.toml",
+		WillCreate:    false,
+		Conflict:      true,
+		HistoryToAdd:  []string{"v2"},
+		HistoryToSkip: []string{"v1"},
+	}
+	if !reflect.DeepEqual(plan.Configs[0], want) {
+		t.Errorf("PlanImport() = %+v, want %+v", plan.Configs[0], want)
+	}
+}
+
+func TestPlanImportOverwriteDoesNotFailOnConflict(t *testing.T) {
+	lookup := func(name string) (bool, map[string]bool) { return true, map[string]bool{"v1": true, "v2": true} }
+
+	plan, err := PlanImport(makeBundle(), ModeOverwrite, lookup)
+	if err != nil {
+		t.Fatalf("overwrite mode should not fail on conflict: %v", err)
+	}
+	if !plan.Configs[0].Conflict {
+		t.Error("expected Conflict=true to still be reported for visibility")
+	}
+}
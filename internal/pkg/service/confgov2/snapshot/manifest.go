@@ -0,0 +1,120 @@
+// Package snapshot 把一个 app/env 下全部配置文件的完整状态打包成 tar+JSON 快照，推送到 S3 兼容
+// 对象存储（MinIO/COS/OSS），并支持把快照导入回任意集群——这是跨集群搬迁配置（比如 staging 推广到 prod）
+// 的传输载体，也可以单纯当备份用。
+package snapshot
+
+import (
+	"archive/tar"
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"time"
+)
+
+const (
+	bundleFileName      = "bundle.json"
+	bundleFormatVersion = 1
+)
+
+// ConfigurationMeta 是一份配置文件本身的元数据，不含内容（内容在 History 里）
+type ConfigurationMeta struct {
+	Name   string `json:"name"`
+	Format string `json:"format"`
+	Zone   string `json:"zone"`
+}
+
+// HistoryEntry 对应一条 ConfigurationHistory 记录
+type HistoryEntry struct {
+	Version   string    `json:"version"`
+	Content   string    `json:"content"`
+	ChangeLog string    `json:"change_log"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// ResourceBinding 是某个历史版本解析出的 ${resource.xxx} 引用及其取值；SourceID 是导出集群里这条
+// 引用对应的 ConfigResourceValue.ID，导入到另一个集群时原 ID 大概率已经对应不上（或者对应到完全
+// 不相关的资源），必须靠它把 content 里的占位符重写成目标集群重新定位/创建出来的新 ID
+type ResourceBinding struct {
+	HistoryVersion string `json:"history_version"`
+	ResourceValue  string `json:"resource_value"`
+	SourceID       uint   `json:"source_id"`
+}
+
+// PublishPayload 记录最近一次发布的版本和落盘路径
+type PublishPayload struct {
+	Version  string `json:"version"`
+	FilePath string `json:"file_path"`
+}
+
+// ConfigSnapshot 是单个配置文件的完整状态：元数据 + 全部历史版本 + 解析出的资源绑定 + 最近一次发布
+type ConfigSnapshot struct {
+	Configuration    ConfigurationMeta `json:"configuration"`
+	History          []HistoryEntry    `json:"history"`
+	ResourceBindings []ResourceBinding `json:"resource_bindings"`
+	LatestPublish    *PublishPayload   `json:"latest_publish,omitempty"`
+}
+
+// Bundle 是一次导出的完整内容：同一个 app/env 下所有配置文件的快照
+type Bundle struct {
+	Version   int              `json:"version"`
+	AppName   string           `json:"app_name"`
+	Env       string           `json:"env"`
+	Configs   []ConfigSnapshot `json:"configs"`
+	CreatedAt time.Time        `json:"created_at"`
+}
+
+// Serialize 把 Bundle 编码成 tar 包（内含 bundle.json），并返回内容的 sha256 校验和
+func Serialize(b Bundle) (data []byte, checksum string, err error) {
+	b.Version = bundleFormatVersion
+
+	payload, err := json.MarshalIndent(b, "", "  ")
+	if err != nil {
+		return nil, "", err
+	}
+
+	var buf bytes.Buffer
+	tw := tar.NewWriter(&buf)
+	if err = tw.WriteHeader(&tar.Header{Name: bundleFileName, Size: int64(len(payload)), Mode: 0o644}); err != nil {
+		return nil, "", err
+	}
+	if _, err = tw.Write(payload); err != nil {
+		return nil, "", err
+	}
+	if err = tw.Close(); err != nil {
+		return nil, "", err
+	}
+
+	sum := sha256.Sum256(buf.Bytes())
+	return buf.Bytes(), hex.EncodeToString(sum[:]), nil
+}
+
+// Deserialize 从 tar 包里还原出 Bundle
+func Deserialize(data []byte) (Bundle, error) {
+	tr := tar.NewReader(bytes.NewReader(data))
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return Bundle{}, err
+		}
+		if hdr.Name != bundleFileName {
+			continue
+		}
+		var b Bundle
+		if err := json.NewDecoder(tr).Decode(&b); err != nil {
+			return Bundle{}, err
+		}
+		return b, nil
+	}
+	return Bundle{}, fmt.Errorf("snapshot: %s 不在归档内", bundleFileName)
+}
+
+// ObjectKey 生成本次导出对象在对象存储里的 key，按 app/env/时间戳分层方便按前缀 List
+func ObjectKey(appName, env string, at time.Time) string {
+	return fmt.Sprintf("juno-snapshots/%s/%s/%s.tar", appName, env, at.UTC().Format("20060102T150405Z"))
+}
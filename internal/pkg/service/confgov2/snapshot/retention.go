@@ -0,0 +1,28 @@
+package snapshot
+
+import (
+	"sort"
+	"time"
+)
+
+// RetentionPolicy 控制定时快照保留多少份，KeepCount/KeepForDays 任一条件满足即保留
+type RetentionPolicy struct {
+	KeepCount   int
+	KeepForDays int
+}
+
+// Prune 按 RetentionPolicy 算出哪些历史快照对象应当被清理
+func Prune(objects []ObjectInfo, policy RetentionPolicy, now time.Time) []ObjectInfo {
+	sorted := append([]ObjectInfo(nil), objects...)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i].ModTime.After(sorted[j].ModTime) })
+
+	var toPrune []ObjectInfo
+	for i, obj := range sorted {
+		keepByCount := policy.KeepCount > 0 && i < policy.KeepCount
+		keepByAge := policy.KeepForDays > 0 && now.Sub(obj.ModTime) <= time.Duration(policy.KeepForDays)*24*time.Hour
+		if !keepByCount && !keepByAge {
+			toPrune = append(toPrune, obj)
+		}
+	}
+	return toPrune
+}
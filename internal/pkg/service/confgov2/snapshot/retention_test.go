@@ -0,0 +1,53 @@
+package snapshot
+
+import (
+	"testing"
+	"time"
+)
+
+func TestPrune(t *testing.T) {
+	now := time.Date(2026, 7, 26, 0, 0, 0, 0, time.UTC)
+	objects := []ObjectInfo{
+		{Key: "day-0", ModTime: now},
+		{Key: "day-1", ModTime: now.AddDate(0, 0, -1)},
+		{Key: "day-2", ModTime: now.AddDate(0, 0, -2)},
+		{Key: "day-3", ModTime: now.AddDate(0, 0, -3)},
+		{Key: "day-10", ModTime: now.AddDate(0, 0, -10)},
+	}
+
+	cases := []struct {
+		name   string
+		policy RetentionPolicy
+		want   []string
+	}{
+		{"KeepCount保留最新2份", RetentionPolicy{KeepCount: 2}, []string{"day-2", "day-3", "day-10"}},
+		{"KeepForDays保留3天内", RetentionPolicy{KeepForDays: 3}, []string{"day-10"}},
+		{"两个条件任一满足即保留", RetentionPolicy{KeepCount: 1, KeepForDays: 3}, []string{"day-10"}},
+		{"都不设置则全部清理", RetentionPolicy{}, []string{"day-0", "day-1", "day-2", "day-3", "day-10"}},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			pruned := Prune(objects, c.policy, now)
+			gotKeys := make([]string, 0, len(pruned))
+			for _, obj := range pruned {
+				gotKeys = append(gotKeys, obj.Key)
+			}
+			if !equalKeys(gotKeys, c.want) {
+				t.Errorf("Prune() = %v, want %v", gotKeys, c.want)
+			}
+		})
+	}
+}
+
+func equalKeys(got, want []string) bool {
+	if len(got) != len(want) {
+		return false
+	}
+	for i := range got {
+		if got[i] != want[i] {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,391 @@
+package confgov2
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/douyu/juno/internal/pkg/service/confgov2/snapshot"
+	"github.com/douyu/juno/pkg/cfg"
+	"github.com/douyu/juno/pkg/model/db"
+	"github.com/douyu/jupiter/pkg/xlog"
+	"github.com/labstack/echo/v4"
+)
+
+var (
+	snapshotStoreOnce sync.Once
+	snapshotStore     snapshot.Store
+	snapshotStoreErr  error
+)
+
+func getSnapshotStore() (snapshot.Store, error) {
+	snapshotStoreOnce.Do(func() {
+		snapshotStore, snapshotStoreErr = snapshot.NewS3Store(snapshot.S3Config{
+			Endpoint:  cfg.Cfg.Snapshot.Endpoint,
+			AccessKey: cfg.Cfg.Snapshot.AccessKey,
+			SecretKey: cfg.Cfg.Snapshot.SecretKey,
+			Bucket:    cfg.Cfg.Snapshot.Bucket,
+			UseSSL:    cfg.Cfg.Snapshot.UseSSL,
+		})
+	})
+	return snapshotStore, snapshotStoreErr
+}
+
+// ExportSnapshot 把 appName/env 下全部配置文件打包成一个 tar+JSON 快照，推到 S3 兼容对象存储，
+// 返回对象 key 和内容校验和；这个快照既可以当备份，也是 ImportSnapshot 跨集群搬迁配置的传输载体
+func ExportSnapshot(c echo.Context, appName, env string) (objectKey, checksum string, err error) {
+	var app db.AppInfo
+	if err = mysql.Where("app_name = ?", appName).First(&app).Error; err != nil {
+		return
+	}
+
+	if _, err = requireAction(c, ActionRead, appName, env, ""); err != nil {
+		return
+	}
+
+	bundle, err := buildBundle(uint(app.Aid), appName, env)
+	if err != nil {
+		return
+	}
+
+	objectKey, checksum, err = persistBundle(bundle)
+	return
+}
+
+func buildBundle(aid uint, appName, env string) (snapshot.Bundle, error) {
+	var configs []db.Configuration
+	if err := mysql.Where("aid = ? and env = ? and deleted_at is null", aid, env).Find(&configs).Error; err != nil {
+		return snapshot.Bundle{}, err
+	}
+
+	bundle := snapshot.Bundle{AppName: appName, Env: env, CreatedAt: time.Now()}
+
+	for _, configuration := range configs {
+		var history []db.ConfigurationHistory
+		if err := mysql.Where("configuration_id = ?", configuration.ID).Order("id asc").Find(&history).Error; err != nil {
+			return snapshot.Bundle{}, err
+		}
+
+		entries := make([]snapshot.HistoryEntry, 0, len(history))
+		var bindings []snapshot.ResourceBinding
+		for _, h := range history {
+			entries = append(entries, snapshot.HistoryEntry{
+				Version:   h.Version,
+				Content:   h.Content,
+				ChangeLog: h.ChangeLog,
+				CreatedAt: h.CreatedAt,
+			})
+
+			resourceValues, err := ParseConfigResourceValuesFromConfig(h)
+			if err != nil {
+				return snapshot.Bundle{}, err
+			}
+			for _, rv := range resourceValues {
+				bindings = append(bindings, snapshot.ResourceBinding{
+					HistoryVersion: h.Version,
+					ResourceValue:  rv.Value,
+					SourceID:       rv.ID,
+				})
+			}
+		}
+
+		var latest *snapshot.PublishPayload
+		var cp db.ConfigurationPublish
+		if mysql.Where("configuration_id = ?", configuration.ID).Order("id desc").First(&cp).Error == nil {
+			var published db.ConfigurationHistory
+			if mysql.Where("id = ?", cp.ConfigurationHistoryID).First(&published).Error == nil {
+				latest = &snapshot.PublishPayload{Version: published.Version, FilePath: cp.FilePath}
+			}
+		}
+
+		bundle.Configs = append(bundle.Configs, snapshot.ConfigSnapshot{
+			Configuration: snapshot.ConfigurationMeta{
+				Name:   configuration.Name,
+				Format: configuration.Format,
+				Zone:   configuration.Zone,
+			},
+			History:          entries,
+			ResourceBindings: bindings,
+			LatestPublish:    latest,
+		})
+	}
+
+	return bundle, nil
+}
+
+func persistBundle(bundle snapshot.Bundle) (objectKey, checksum string, err error) {
+	data, checksum, err := snapshot.Serialize(bundle)
+	if err != nil {
+		return "", "", err
+	}
+
+	store, err := getSnapshotStore()
+	if err != nil {
+		return "", "", err
+	}
+
+	objectKey = snapshot.ObjectKey(bundle.AppName, bundle.Env, bundle.CreatedAt)
+	if err = store.Put(context.Background(), objectKey, data); err != nil {
+		return "", "", err
+	}
+
+	if err = mysql.Save(&db.ConfigurationSnapshot{
+		AppName:   bundle.AppName,
+		Env:       bundle.Env,
+		ObjectKey: objectKey,
+		Checksum:  checksum,
+		Size:      int64(len(data)),
+		CreatedAt: bundle.CreatedAt,
+	}).Error; err != nil {
+		return "", "", err
+	}
+
+	return objectKey, checksum, nil
+}
+
+// ListSnapshots 列出某个 app/env 下已经导出过的快照
+func ListSnapshots(c echo.Context, appName, env string) (list []db.ConfigurationSnapshot, err error) {
+	if _, err = requireAction(c, ActionRead, appName, env, ""); err != nil {
+		return
+	}
+	err = mysql.Where("app_name = ? and env = ?", appName, env).Order("id desc").Find(&list).Error
+	return
+}
+
+// ImportSnapshot 把一份快照导入到 targetEnv/targetZone；dry-run 只返回导入计划，create 在冲突时报错，
+// overwrite 用快照内容整体覆盖，merge 只补齐目标环境缺少的历史版本
+func ImportSnapshot(c echo.Context, objectKey, targetEnv, targetZone string, mode snapshot.Mode, user *db.User) (plan snapshot.Plan, err error) {
+	store, err := getSnapshotStore()
+	if err != nil {
+		return
+	}
+
+	data, err := store.Get(context.Background(), objectKey)
+	if err != nil {
+		return
+	}
+
+	bundle, err := snapshot.Deserialize(data)
+	if err != nil {
+		return
+	}
+
+	if _, err = requireAction(c, ActionEditDraft, bundle.AppName, targetEnv, targetZone); err != nil {
+		return
+	}
+
+	var app db.AppInfo
+	if err = mysql.Where("app_name = ?", bundle.AppName).First(&app).Error; err != nil {
+		return
+	}
+
+	lookup := func(name string) (bool, map[string]bool) {
+		var existing db.Configuration
+		if mysql.Where("aid = ? and env = ? and zone = ? and name = ?", app.Aid, targetEnv, targetZone, name).
+			First(&existing).Error != nil {
+			return false, nil
+		}
+		var history []db.ConfigurationHistory
+		mysql.Where("configuration_id = ?", existing.ID).Find(&history)
+		versions := make(map[string]bool, len(history))
+		for _, h := range history {
+			versions[h.Version] = true
+		}
+		return true, versions
+	}
+
+	plan, err = snapshot.PlanImport(bundle, mode, lookup)
+	if err != nil || mode == snapshot.ModeDryRun {
+		return
+	}
+
+	for _, configSnapshot := range bundle.Configs {
+		if err = applyConfigSnapshot(app, targetEnv, targetZone, configSnapshot, mode, user); err != nil {
+			return
+		}
+	}
+
+	return plan, nil
+}
+
+func applyConfigSnapshot(app db.AppInfo, targetEnv, targetZone string, snap snapshot.ConfigSnapshot, mode snapshot.Mode, user *db.User) error {
+	var configuration db.Configuration
+	exists := mysql.Where("aid = ? and env = ? and zone = ? and name = ?",
+		app.Aid, targetEnv, targetZone, snap.Configuration.Name).First(&configuration).Error == nil
+
+	if !exists {
+		configuration = db.Configuration{
+			AID:    uint(app.Aid),
+			Name:   snap.Configuration.Name,
+			Format: snap.Configuration.Format,
+			Env:    targetEnv,
+			Zone:   targetZone,
+		}
+		if err := mysql.Create(&configuration).Error; err != nil {
+			return err
+		}
+	} else if mode == snapshot.ModeCreate {
+		return fmt.Errorf("配置 %s 已存在", snap.Configuration.Name)
+	}
+
+	var existingVersions map[string]bool
+	if mode == snapshot.ModeMerge {
+		var history []db.ConfigurationHistory
+		mysql.Where("configuration_id = ?", configuration.ID).Find(&history)
+		existingVersions = make(map[string]bool, len(history))
+		for _, h := range history {
+			existingVersions[h.Version] = true
+		}
+	}
+
+	if mode == snapshot.ModeOverwrite && exists {
+		// overwrite 是整体替换历史：先清掉旧的历史记录和资源绑定关系，否则下面的 Save 只会在已有记录旁边
+		// 追加出重复的版本，而不是真正"替换"
+		var oldHistory []db.ConfigurationHistory
+		mysql.Where("configuration_id = ?", configuration.ID).Find(&oldHistory)
+		for _, h := range oldHistory {
+			mysql.Where("configuration_history_id = ?", h.ID).Delete(&db.ConfigurationResourceRelation{})
+		}
+		if err := mysql.Where("configuration_id = ?", configuration.ID).Delete(&db.ConfigurationHistory{}).Error; err != nil {
+			return err
+		}
+	}
+
+	var latestContent string
+	for _, h := range snap.History {
+		if mode == snapshot.ModeMerge && existingVersions[h.Version] {
+			continue
+		}
+
+		content, resourceValueIDs, err := remapResourceContent(h.Content, uint(app.Aid), bindingsForVersion(snap.ResourceBindings, h.Version))
+		if err != nil {
+			return fmt.Errorf("配置 %s 版本 %s 的资源引用无法重映射: %w", snap.Configuration.Name, h.Version, err)
+		}
+
+		history := db.ConfigurationHistory{
+			ConfigurationID: configuration.ID,
+			ChangeLog:       h.ChangeLog,
+			Content:         content,
+			Version:         h.Version,
+			UID:             uint(user.Uid),
+		}
+		if err := mysql.Save(&history).Error; err != nil {
+			return err
+		}
+
+		for _, resourceValueID := range resourceValueIDs {
+			if err := mysql.Save(&db.ConfigurationResourceRelation{
+				ConfigurationHistoryID: history.ID,
+				ConfigResourceValueID:  resourceValueID,
+			}).Error; err != nil {
+				return err
+			}
+		}
+
+		latestContent = content
+	}
+
+	if mode == snapshot.ModeOverwrite && latestContent != "" {
+		configuration.Content = latestContent
+		return mysql.Save(&configuration).Error
+	}
+
+	return nil
+}
+
+func bindingsForVersion(bindings []snapshot.ResourceBinding, version string) []snapshot.ResourceBinding {
+	var out []snapshot.ResourceBinding
+	for _, b := range bindings {
+		if b.HistoryVersion == version {
+			out = append(out, b)
+		}
+	}
+	return out
+}
+
+// remapResourceContent 把 content 里每个 ${resource.<来源ID>} 占位符重写成目标集群里重新定位/创建出来的
+// ConfigResourceValue.ID：来源 ID 是导出集群的全局自增 ID，原样落到目标集群大概率对应不上（或者对应到
+// 完全不相关的资源），必须按 (aid, value) 重新定位，找不到就新建一条再换成新 ID，否则发布时
+// FillConfigResource/ResourceValidator 按旧 ID 完全查不到，配置要么发布失败要么带着裸占位符上线。
+// binding 缺失来源 ID 说明导出时那份快照本身就是老格式，没法安全重映射，直接拒绝这条历史记录而不是
+// 悄悄留下一个解析不到的占位符
+func remapResourceContent(content string, aid uint, bindings []snapshot.ResourceBinding) (string, []uint, error) {
+	resourceValueIDs := make([]uint, 0, len(bindings))
+	for _, binding := range bindings {
+		if binding.SourceID == 0 {
+			return "", nil, fmt.Errorf("资源绑定 %q 缺少来源 ID，无法安全重映射占位符", binding.ResourceValue)
+		}
+
+		var value db.ConfigResourceValue
+		if mysql.Where("aid = ? and value = ?", aid, binding.ResourceValue).First(&value).Error != nil {
+			value = db.ConfigResourceValue{AID: aid, Value: binding.ResourceValue}
+			if err := mysql.Create(&value).Error; err != nil {
+				return "", nil, err
+			}
+		}
+
+		content = strings.ReplaceAll(content,
+			fmt.Sprintf("${resource.%d}", binding.SourceID),
+			fmt.Sprintf("${resource.%d}", value.ID))
+		resourceValueIDs = append(resourceValueIDs, value.ID)
+	}
+	return content, resourceValueIDs, nil
+}
+
+// ScheduleDailySnapshot 启动一个按天导出快照并按 policy 清理过期快照的后台 ticker；
+// 节点之间不做抢占，每个调用它的 juno-admin 节点都会各自导出一份快照
+func ScheduleDailySnapshot(ctx context.Context, appName, env string, policy snapshot.RetentionPolicy) {
+	go func() {
+		ticker := time.NewTicker(24 * time.Hour)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				if err := snapshotAndPrune(appName, env, policy); err != nil {
+					xlog.Error("ScheduleDailySnapshot", xlog.String("error", err.Error()))
+				}
+			}
+		}
+	}()
+}
+
+func snapshotAndPrune(appName, env string, policy snapshot.RetentionPolicy) error {
+	var app db.AppInfo
+	if err := mysql.Where("app_name = ?", appName).First(&app).Error; err != nil {
+		return err
+	}
+
+	bundle, err := buildBundle(uint(app.Aid), appName, env)
+	if err != nil {
+		return err
+	}
+
+	if _, _, err := persistBundle(bundle); err != nil {
+		return err
+	}
+
+	store, err := getSnapshotStore()
+	if err != nil {
+		return err
+	}
+
+	objects, err := store.List(context.Background(), fmt.Sprintf("juno-snapshots/%s/%s/", appName, env))
+	if err != nil {
+		return err
+	}
+
+	for _, obj := range snapshot.Prune(objects, policy, time.Now()) {
+		if err := store.Delete(context.Background(), obj.Key); err != nil {
+			xlog.Error("snapshotAndPrune", xlog.String("error", err.Error()))
+			continue
+		}
+		mysql.Where("object_key = ?", obj.Key).Delete(&db.ConfigurationSnapshot{})
+	}
+
+	return nil
+}
@@ -0,0 +1,94 @@
+// Package validate 实现配置发布前的校验流水线：按文件/格式注册一串 Validator，
+// 依次跑完后汇总成 Report，供 confgov2.Update/Publish 在写入 etcd 前把关。
+package validate
+
+import (
+	"context"
+	"fmt"
+)
+
+// Severity 标记一条校验结果的严重程度
+type Severity string
+
+const (
+	SeverityError   Severity = "error"
+	SeverityWarning Severity = "warning"
+)
+
+// Issue 是单条校验器输出的问题，Fix 为空表示没有可自动应用的修复建议
+type Issue struct {
+	Validator string   `json:"validator"`
+	Severity  Severity `json:"severity"`
+	Message   string   `json:"message"`
+	Fix       string   `json:"fix,omitempty"`
+}
+
+// Report 是整条校验链跑完后的汇总结果
+type Report struct {
+	Errors   []Issue  `json:"errors"`
+	Warnings []Issue  `json:"warnings"`
+	Fixes    []string `json:"fixes,omitempty"`
+}
+
+// HasErrors 返回 true 表示本次内容不应被发布，除非调用方持有 override_validation 权限
+func (r Report) HasErrors() bool {
+	return len(r.Errors) > 0
+}
+
+// Input 是一次校验所需的最小上下文
+type Input struct {
+	AID     uint
+	Name    string
+	Format  string
+	Content string
+}
+
+// Validator 是校验链中的一环，Name 用于在 Issue/日志中标识来源
+type Validator interface {
+	Name() string
+	Validate(ctx context.Context, in Input) ([]Issue, error)
+}
+
+// Chain 按注册顺序依次执行一组 Validator
+type Chain struct {
+	validators []Validator
+}
+
+// NewChain 按给定顺序组装校验链
+func NewChain(validators ...Validator) *Chain {
+	return &Chain{validators: validators}
+}
+
+// Run 依次执行所有校验器；某个校验器自身出错（而非发现问题）时，当作一条 error 级别的 Issue 处理，
+// 不会中断后续校验器的执行，以便一次性收集全部问题反馈给前端
+func (c *Chain) Run(ctx context.Context, in Input) Report {
+	report := Report{}
+
+	for _, v := range c.validators {
+		issues, err := v.Validate(ctx, in)
+		if err != nil {
+			report.Errors = append(report.Errors, Issue{
+				Validator: v.Name(),
+				Severity:  SeverityError,
+				Message:   fmt.Sprintf("%s 校验器执行失败: %s", v.Name(), err.Error()),
+			})
+			continue
+		}
+
+		for _, issue := range issues {
+			issue.Validator = v.Name()
+			switch issue.Severity {
+			case SeverityWarning:
+				report.Warnings = append(report.Warnings, issue)
+			default:
+				issue.Severity = SeverityError
+				report.Errors = append(report.Errors, issue)
+			}
+			if issue.Fix != "" {
+				report.Fixes = append(report.Fixes, issue.Fix)
+			}
+		}
+	}
+
+	return report
+}
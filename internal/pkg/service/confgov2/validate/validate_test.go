@@ -0,0 +1,67 @@
+package validate
+
+import (
+	"context"
+	"errors"
+	"testing"
+)
+
+type fakeValidator struct {
+	name   string
+	issues []Issue
+	err    error
+}
+
+func (f fakeValidator) Name() string { return f.name }
+
+func (f fakeValidator) Validate(_ context.Context, _ Input) ([]Issue, error) {
+	return f.issues, f.err
+}
+
+func TestChainRunRoutesBySeverity(t *testing.T) {
+	chain := NewChain(
+		fakeValidator{name: "a", issues: []Issue{{Severity: SeverityError, Message: "bad"}}},
+		fakeValidator{name: "b", issues: []Issue{{Severity: SeverityWarning, Message: "maybe"}}},
+	)
+
+	report := chain.Run(context.Background(), Input{})
+
+	if !report.HasErrors() {
+		t.Fatal("expected HasErrors() to be true when an error-severity issue is present")
+	}
+	if len(report.Errors) != 1 || report.Errors[0].Validator != "a" {
+		t.Errorf("report.Errors = %+v, want one issue from validator a", report.Errors)
+	}
+	if len(report.Warnings) != 1 || report.Warnings[0].Validator != "b" {
+		t.Errorf("report.Warnings = %+v, want one issue from validator b", report.Warnings)
+	}
+}
+
+func TestChainRunValidatorErrorBecomesErrorIssueAndContinues(t *testing.T) {
+	chain := NewChain(
+		fakeValidator{name: "broken", err: errors.New("boom")},
+		fakeValidator{name: "ok", issues: []Issue{{Severity: SeverityWarning, Message: "fine"}}},
+	)
+
+	report := chain.Run(context.Background(), Input{})
+
+	if !report.HasErrors() {
+		t.Fatal("a validator returning err should surface as an error-severity issue")
+	}
+	if report.Errors[0].Validator != "broken" {
+		t.Errorf("report.Errors[0].Validator = %q, want %q", report.Errors[0].Validator, "broken")
+	}
+	if len(report.Warnings) != 1 || report.Warnings[0].Validator != "ok" {
+		t.Error("a failing validator should not stop later validators in the chain from running")
+	}
+}
+
+func TestChainRunDefaultsUnknownSeverityToError(t *testing.T) {
+	chain := NewChain(fakeValidator{name: "a", issues: []Issue{{Severity: "", Message: "untyped"}}})
+
+	report := chain.Run(context.Background(), Input{})
+
+	if len(report.Errors) != 1 || len(report.Warnings) != 0 {
+		t.Errorf("an issue with no explicit severity should be treated as an error, got errors=%+v warnings=%+v", report.Errors, report.Warnings)
+	}
+}
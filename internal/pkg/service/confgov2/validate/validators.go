@@ -0,0 +1,199 @@
+package validate
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"github.com/BurntSushi/toml"
+	"gopkg.in/ini.v1"
+	"gopkg.in/yaml.v2"
+)
+
+// SyntaxValidator 做最基础的语法解析校验：保证内容至少是一份合法的 TOML/YAML/JSON/INI
+type SyntaxValidator struct{}
+
+func (SyntaxValidator) Name() string { return "syntax" }
+
+func (SyntaxValidator) Validate(_ context.Context, in Input) ([]Issue, error) {
+	var err error
+	switch in.Format {
+	case "toml":
+		var v map[string]interface{}
+		_, err = toml.Decode(in.Content, &v)
+	case "yaml", "yml":
+		var v map[string]interface{}
+		err = yaml.Unmarshal([]byte(in.Content), &v)
+	case "json":
+		var v map[string]interface{}
+		err = json.Unmarshal([]byte(in.Content), &v)
+	case "ini":
+		_, err = ini.Load([]byte(in.Content))
+	default:
+		return nil, nil
+	}
+
+	if err != nil {
+		return []Issue{{
+			Severity: SeverityError,
+			Message:  fmt.Sprintf("%s 格式解析失败: %s", in.Format, err.Error()),
+		}}, nil
+	}
+	return nil, nil
+}
+
+// SchemaLoaderFunc 按 AID+Name 加载结构化 schema（ConfigurationSchema 表），不存在时 ok=false
+type SchemaLoaderFunc func(aid uint, name string) (schema string, ok bool, err error)
+
+// SchemaValidator 用存放在 ConfigurationSchema 中的结构化约束校验配置内容
+type SchemaValidator struct {
+	Loader SchemaLoaderFunc
+}
+
+func (SchemaValidator) Name() string { return "schema" }
+
+func (v SchemaValidator) Validate(_ context.Context, in Input) ([]Issue, error) {
+	if v.Loader == nil {
+		return nil, nil
+	}
+
+	schema, ok, err := v.Loader(in.AID, in.Name)
+	if err != nil {
+		return nil, err
+	}
+	if !ok || schema == "" {
+		// 没有配置 schema，视为不校验
+		return nil, nil
+	}
+
+	var schemaFields map[string]string
+	if err := json.Unmarshal([]byte(schema), &schemaFields); err != nil {
+		return nil, fmt.Errorf("schema 本身不是合法 JSON: %w", err)
+	}
+
+	var content map[string]interface{}
+	if err := unmarshalByFormat(in.Format, in.Content, &content); err != nil {
+		// 语法都不对的情况交给 SyntaxValidator 报告，这里不重复报错
+		return nil, nil
+	}
+
+	var issues []Issue
+	for field, fieldType := range schemaFields {
+		value, exists := content[field]
+		if !exists {
+			issues = append(issues, Issue{Severity: SeverityError, Message: fmt.Sprintf("缺少必填字段 %q", field)})
+			continue
+		}
+		if !matchesType(value, fieldType) {
+			issues = append(issues, Issue{Severity: SeverityError, Message: fmt.Sprintf("字段 %q 期望类型 %s，实际为 %T", field, fieldType, value)})
+		}
+	}
+
+	return issues, nil
+}
+
+func unmarshalByFormat(format, content string, out *map[string]interface{}) error {
+	switch format {
+	case "json":
+		return json.Unmarshal([]byte(content), out)
+	case "yaml", "yml":
+		return yaml.Unmarshal([]byte(content), out)
+	case "toml":
+		_, err := toml.Decode(content, out)
+		return err
+	default:
+		return fmt.Errorf("schema 校验暂不支持 %s 格式", format)
+	}
+}
+
+func matchesType(value interface{}, expect string) bool {
+	switch expect {
+	case "string":
+		_, ok := value.(string)
+		return ok
+	case "number":
+		switch value.(type) {
+		case int, int64, float64:
+			return true
+		}
+		return false
+	case "bool":
+		_, ok := value.(bool)
+		return ok
+	default:
+		return true
+	}
+}
+
+// ResourceRef 是从配置内容中解析出的一个 ${resource.xxx} 占位符引用
+type ResourceRef struct {
+	Placeholder string
+	Version     uint
+}
+
+// ResourceParserFunc 从配置内容解析出引用的资源占位符列表
+type ResourceParserFunc func(content string) []ResourceRef
+
+// ResourceLookupFunc 按资源版本号查找资源值，找不到时 ok=false；找到但取值为空时返回 value="", ok=true
+type ResourceLookupFunc func(version uint) (value string, ok bool)
+
+// ResourceValidator 校验配置里引用的 ${resource.xxx} 占位符都能解析到非空的资源值
+type ResourceValidator struct {
+	Parse  ResourceParserFunc
+	Lookup ResourceLookupFunc
+}
+
+func (ResourceValidator) Name() string { return "resource-reference" }
+
+func (v ResourceValidator) Validate(_ context.Context, in Input) ([]Issue, error) {
+	if v.Parse == nil || v.Lookup == nil {
+		return nil, nil
+	}
+
+	var issues []Issue
+	for _, ref := range v.Parse(in.Content) {
+		value, ok := v.Lookup(ref.Version)
+		if !ok {
+			issues = append(issues, Issue{Severity: SeverityError, Message: fmt.Sprintf("引用的资源 %s 不存在", ref.Placeholder)})
+			continue
+		}
+		if value == "" {
+			issues = append(issues, Issue{Severity: SeverityWarning, Message: fmt.Sprintf("引用的资源 %s 取值为空", ref.Placeholder)})
+		}
+	}
+	return issues, nil
+}
+
+// CheckFunc 执行一次自定义校验（shell / HTTP，比如调用 agent 的 `app --config-check`），
+// 返回非空字符串表示校验器检测到的具体问题
+type CheckFunc func(ctx context.Context, in Input) (problems []string, err error)
+
+// CustomValidator 包装一个用户自定义校验回调，用于 shell/HTTP 等无法内建支持的检查方式
+type CustomValidator struct {
+	CheckName string
+	Check     CheckFunc
+}
+
+func (v CustomValidator) Name() string {
+	if v.CheckName != "" {
+		return v.CheckName
+	}
+	return "custom"
+}
+
+func (v CustomValidator) Validate(ctx context.Context, in Input) ([]Issue, error) {
+	if v.Check == nil {
+		return nil, nil
+	}
+
+	problems, err := v.Check(ctx, in)
+	if err != nil {
+		return nil, err
+	}
+
+	issues := make([]Issue, 0, len(problems))
+	for _, p := range problems {
+		issues = append(issues, Issue{Severity: SeverityError, Message: p})
+	}
+	return issues, nil
+}
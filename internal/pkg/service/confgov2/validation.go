@@ -0,0 +1,125 @@
+package confgov2
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/douyu/juno/internal/pkg/service/clientproxy"
+	"github.com/douyu/juno/internal/pkg/service/confgov2/validate"
+	"github.com/douyu/juno/internal/pkg/service/configresource"
+	"github.com/douyu/juno/internal/pkg/service/resource"
+	"github.com/douyu/juno/pkg/cfg"
+	"github.com/douyu/juno/pkg/model/db"
+	"github.com/douyu/juno/pkg/model/view"
+	"github.com/jinzhu/gorm"
+)
+
+// buildValidatorChain 组装 Update/Publish 共用的校验链；agentCheck 非空时额外跑一次 agent 侧的
+// `app --config-check`，Update 阶段配置还未关联任何运行实例，调用方应传 nil 跳过这一项
+func buildValidatorChain(configuration db.Configuration, agentCheck validate.CheckFunc) *validate.Chain {
+	validators := []validate.Validator{
+		validate.SyntaxValidator{},
+		validate.SchemaValidator{Loader: loadConfigurationSchema},
+		validate.ResourceValidator{Parse: parseResourceRefs, Lookup: lookupResourceValue},
+	}
+	if agentCheck != nil {
+		validators = append(validators, validate.CustomValidator{CheckName: "agent-config-check", Check: agentCheck})
+	}
+	return validate.NewChain(validators...)
+}
+
+func loadConfigurationSchema(aid uint, name string) (schema string, ok bool, err error) {
+	var row db.ConfigurationSchema
+	err = mysql.Where("aid = ? and name = ?", aid, name).First(&row).Error
+	if err != nil {
+		if gorm.IsRecordNotFoundError(err) {
+			return "", false, nil
+		}
+		return "", false, err
+	}
+	return row.Schema, true, nil
+}
+
+func parseResourceRefs(content string) []validate.ResourceRef {
+	resources := configresource.ParseResourceFromConfig(content)
+	refs := make([]validate.ResourceRef, 0, len(resources))
+	for _, res := range resources {
+		refs = append(refs, validate.ResourceRef{
+			Placeholder: fmt.Sprintf("resource#%d", res.Version),
+			Version:     res.Version,
+		})
+	}
+	return refs
+}
+
+func lookupResourceValue(version uint) (value string, ok bool) {
+	var rv db.ConfigResourceValue
+	if err := mysql.Where("id = ?", version).First(&rv).Error; err != nil {
+		return "", false
+	}
+	return rv.Value, true
+}
+
+// newAgentConfigCheck 构造一个通过 agent 调用 `app --config-check` 的自定义校验回调，
+// 挑选 instanceList 中的第一个实例执行，避免对每个实例都跑一遍
+func newAgentConfigCheck(appName string, instanceList []string) validate.CheckFunc {
+	if len(instanceList) == 0 {
+		return nil
+	}
+	hostName := instanceList[0]
+
+	return func(ctx context.Context, in validate.Input) ([]string, error) {
+		nodes, err := resource.Resource.GetAllAppNodeList(db.AppNode{HostName: hostName})
+		if err != nil || len(nodes) == 0 {
+			return nil, err
+		}
+		node := nodes[0]
+
+		req := view.ReqHTTPProxy{
+			Address: fmt.Sprintf("%s:%d", node.IP, cfg.Cfg.Agent.Port),
+			URL:     "/api/agent/config_check",
+			Type:    http.MethodPost,
+			Params: map[string]string{
+				"app_name": appName,
+				"format":   in.Format,
+				"content":  in.Content,
+			},
+		}
+
+		resp, err := clientproxy.ClientProxy.HttpGet(view.UniqZone{Env: node.Env, Zone: node.ZoneCode}, req)
+		if err != nil {
+			return nil, err
+		}
+
+		var result struct {
+			Code int      `json:"code"`
+			Msg  string   `json:"msg"`
+			Data []string `json:"data"`
+		}
+		if err := json.Unmarshal(resp.Body(), &result); err != nil {
+			return nil, err
+		}
+		if result.Code != 200 {
+			return []string{result.Msg}, nil
+		}
+		return result.Data, nil
+	}
+}
+
+// saveValidationResult 持久化一次校验结果，便于 History/Diff 视图展示过往的校验记录
+func saveValidationResult(configuration db.Configuration, report validate.Report) error {
+	payload, err := json.Marshal(report)
+	if err != nil {
+		return err
+	}
+
+	return mysql.Save(&db.ConfigurationValidationResult{
+		ConfigurationID: configuration.ID,
+		Passed:          !report.HasErrors(),
+		Report:          string(payload),
+		CreatedAt:       time.Now(),
+	}).Error
+}